@@ -0,0 +1,41 @@
+package main
+
+import (
+	"errors"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// clipboardCommands lists the commands that can write stdin to the system
+// clipboard on each platform, in order of preference
+var clipboardCommands = map[string][][]string{
+	"darwin":  {{"pbcopy"}},
+	"windows": {{"clip.exe"}},
+	"linux": {
+		{"wl-copy"},
+		{"xclip", "-selection", "clipboard"},
+		{"xsel", "--clipboard", "--input"},
+	},
+}
+
+// copyToClipboard writes text to the system clipboard using the first
+// available platform clipboard command
+func copyToClipboard(text string) error {
+	commands, ok := clipboardCommands[runtime.GOOS]
+	if !ok {
+		commands = clipboardCommands["linux"]
+	}
+
+	for _, command := range commands {
+		path, err := exec.LookPath(command[0])
+		if err != nil {
+			continue
+		}
+		cmd := exec.Command(path, command[1:]...)
+		cmd.Stdin = strings.NewReader(text)
+		return cmd.Run()
+	}
+
+	return errors.New("no clipboard command available")
+}
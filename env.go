@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// envFormats maps MDQ_FORMAT values to their output format flags
+var envFormats = map[string]string{
+	"text":     "",
+	"json":     "--json",
+	"jsonl":    "--jsonl",
+	"csv":      "--csv",
+	"tsv":      "--tsv",
+	"yaml":     "--yaml",
+	"markdown": "--markdown",
+	"html":     "--html",
+}
+
+// envArgs returns the default flags supplied by the environment:
+// MDQ_FORMAT, MDQ_NO_BLOCKS, and then anything in MDQ_OPTS
+func envArgs() ([]string, error) {
+	var args []string
+
+	if format := os.Getenv("MDQ_FORMAT"); format != "" {
+		flagName, ok := envFormats[strings.ToLower(format)]
+		if !ok {
+			return nil, fmt.Errorf("MDQ_FORMAT: unknown format %q (use text, json, jsonl, csv, tsv, yaml, markdown, or html)", format)
+		}
+		if flagName != "" {
+			args = append(args, flagName)
+		}
+	}
+
+	if noBlocks := os.Getenv("MDQ_NO_BLOCKS"); noBlocks != "" {
+		enabled, err := strconv.ParseBool(noBlocks)
+		if err != nil {
+			return nil, fmt.Errorf("MDQ_NO_BLOCKS: %v", err)
+		}
+		if enabled {
+			args = append(args, "--no-blocks")
+		}
+	}
+
+	opts, err := splitArgs(os.Getenv("MDQ_OPTS"))
+	if err != nil {
+		return nil, fmt.Errorf("MDQ_OPTS: %v", err)
+	}
+	return append(args, opts...), nil
+}
+
+// splitArgs splits a string into arguments on whitespace, keeping
+// single- or double-quoted text together
+func splitArgs(s string) ([]string, error) {
+	var args []string
+	var current strings.Builder
+	inArg := false
+	var quote rune
+
+	for _, r := range s {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				current.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			inArg = true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		default:
+			current.WriteRune(r)
+			inArg = true
+		}
+	}
+
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated quote in %q", s)
+	}
+	if inArg {
+		args = append(args, current.String())
+	}
+	return args, nil
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// hasGlobMeta reports whether a path contains glob metacharacters
+func hasGlobMeta(path string) bool {
+	return strings.ContainsAny(path, "*?[")
+}
+
+// expandFileArgs expands glob patterns that the shell left unexpanded
+// (e.g. cmd.exe on Windows passes "*.md" literally). A pattern is only
+// expanded when no file exists with that literal name.
+func expandFileArgs(args []string) []string {
+	var files []string
+	for _, arg := range args {
+		if !hasGlobMeta(arg) {
+			files = append(files, arg)
+			continue
+		}
+
+		// A file literally named like a pattern takes precedence
+		if _, err := os.Stat(arg); err == nil {
+			files = append(files, arg)
+			continue
+		}
+
+		matches, err := filepath.Glob(arg)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: invalid pattern %s: %v\n", arg, err)
+			continue
+		}
+		if len(matches) == 0 {
+			fmt.Fprintf(os.Stderr, "Warning: pattern %s matched no files\n", arg)
+			continue
+		}
+		files = append(files, matches...)
+	}
+	return files
+}
+
+// expandDirectories replaces directory arguments with the markdown files
+// beneath them, in lexical order. Files with an extension in extensions
+// are kept; other arguments pass through unchanged.
+func expandDirectories(args []string, extensions []string) []string {
+	var files []string
+	for _, arg := range args {
+		info, err := os.Stat(arg)
+		if err != nil || !info.IsDir() {
+			files = append(files, arg)
+			continue
+		}
+		files = append(files, walkMarkdown(arg, extensions, make(map[string]bool))...)
+	}
+	return files
+}
+
+// walkMarkdown finds the markdown files under root. Symlinked directories
+// are followed, but each real directory is visited only once so symlink
+// loops end. A symlinked root is walked through its target, with paths
+// still reported under root.
+func walkMarkdown(root string, extensions []string, visited map[string]bool) []string {
+	walkRoot := root
+	if real, err := filepath.EvalSymlinks(root); err == nil {
+		if visited[real] {
+			return nil
+		}
+		visited[real] = true
+		walkRoot = real
+	}
+
+	var files []string
+	err := filepath.WalkDir(walkRoot, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+			return nil
+		}
+		if d.IsDir() {
+			if path == walkRoot {
+				return nil
+			}
+			if real, err := filepath.EvalSymlinks(path); err == nil {
+				if visited[real] {
+					return filepath.SkipDir
+				}
+				visited[real] = true
+			}
+			return nil
+		}
+		path = filepath.Join(root, strings.TrimPrefix(path, walkRoot))
+
+		// WalkDir doesn't follow symlinks, so resolve them here
+		if d.Type()&fs.ModeSymlink != 0 {
+			info, err := os.Stat(path)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+				return nil
+			}
+			if info.IsDir() {
+				files = append(files, walkMarkdown(path, extensions, visited)...)
+				return nil
+			}
+		}
+
+		if hasExtension(path, extensions) {
+			files = append(files, path)
+		}
+		return nil
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+	return files
+}
+
+// hasExtension reports whether a path ends in one of the extensions,
+// ignoring case
+func hasExtension(path string, extensions []string) bool {
+	ext := filepath.Ext(path)
+	for _, candidate := range extensions {
+		if strings.EqualFold(ext, candidate) {
+			return true
+		}
+	}
+	return false
+}
+
+// readFileList reads newline-separated file paths from path, or from stdin
+// when path is "-", skipping empty lines
+func readFileList(path string) ([]string, error) {
+	var r io.Reader = os.Stdin
+	if path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		r = file
+	}
+
+	var files []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if line := strings.TrimRight(scanner.Text(), "\r"); strings.TrimSpace(line) != "" {
+			files = append(files, line)
+		}
+	}
+	return files, scanner.Err()
+}
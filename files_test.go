@@ -0,0 +1,43 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestWalkMarkdownSymlinkedRoot(t *testing.T) {
+	dir := t.TempDir()
+	real := filepath.Join(dir, "real")
+	if err := os.MkdirAll(filepath.Join(real, "sub"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"a.md", "notes.txt", filepath.Join("sub", "b.md")} {
+		if err := os.WriteFile(filepath.Join(real, name), []byte("# T\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	link := filepath.Join(dir, "link")
+	if err := os.Symlink(real, link); err != nil {
+		t.Skipf("symlinks unavailable: %v", err)
+	}
+	// A loop back to the root is only walked once
+	if err := os.Symlink(real, filepath.Join(real, "sub", "loop")); err != nil {
+		t.Fatal(err)
+	}
+
+	extensions := []string{".md"}
+	tests := []struct {
+		root string
+		want []string
+	}{
+		{real, []string{filepath.Join(real, "a.md"), filepath.Join(real, "sub", "b.md")}},
+		{link, []string{filepath.Join(link, "a.md"), filepath.Join(link, "sub", "b.md")}},
+	}
+	for _, tt := range tests {
+		if got := walkMarkdown(tt.root, extensions, make(map[string]bool)); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("walkMarkdown(%s) = %q, want %q", tt.root, got, tt.want)
+		}
+	}
+}
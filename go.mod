@@ -2,4 +2,9 @@ module github.com/disser/mdq
 
 go 1.23
 
-require gopkg.in/yaml.v3 v3.0.1
+require (
+	github.com/BurntSushi/toml v1.5.0
+	github.com/yuin/goldmark v1.8.6
+	golang.org/x/text v0.22.0
+	gopkg.in/yaml.v3 v3.0.1
+)
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"errors"
+	"os"
+
+	"github.com/disser/mdq/mdq"
+)
+
+// loadedFile is the outcome of reading and parsing one file
+type loadedFile struct {
+	doc     *mdq.Document
+	readErr error // The file couldn't be opened
+	err     error // The file couldn't be parsed
+}
+
+// loadOptions are the settings every document is parsed and prepared with
+type loadOptions struct {
+	noBlocks   bool
+	onlyBlocks bool
+	codeBlocks mdq.CodeBlockOptions // What --only-blocks keeps
+	nested     bool
+	defaults   map[string]interface{}
+	maxDepth   int
+}
+
+// prepareDocument applies the frontmatter defaults, depth limit, and body
+// settings that every document gets after parsing
+func prepareDocument(doc *mdq.Document, opts loadOptions) {
+	mdq.MergeFrontmatter(doc, opts.defaults)
+	mdq.LimitFrontmatterDepth(doc, opts.maxDepth)
+	if opts.onlyBlocks {
+		mdq.KeepOnlyCodeBlocks(doc, opts.codeBlocks)
+	}
+	if opts.nested {
+		mdq.UseNestedBodies(doc)
+	}
+}
+
+// loadFile reads, parses, and prepares one file
+func loadFile(filePath string, opts loadOptions) loadedFile {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return loadedFile{readErr: err}
+	}
+	if info, err := file.Stat(); err == nil && info.IsDir() {
+		file.Close()
+		return loadedFile{readErr: errors.New("is a directory (use -R to query the files in it)")}
+	}
+
+	// Parse the file as it is read rather than loading it whole
+	doc, err := mdq.ParseDocumentReader(file, filePath, opts.noBlocks)
+	file.Close()
+	if err != nil {
+		return loadedFile{err: err}
+	}
+	prepareDocument(doc, opts)
+
+	// Record the modification time for the mtime pseudo-field
+	if info, err := os.Stat(filePath); err == nil {
+		doc.ModTime = info.ModTime()
+	}
+	return loadedFile{doc: doc}
+}
+
+// loadFiles loads files on up to jobs goroutines. The returned channels
+// yield each file's result in argument order, so callers read them in
+// sequence and output stays deterministic. Closing stop keeps files that
+// haven't started yet from being loaded.
+func loadFiles(files []string, jobs int, stop <-chan struct{}, load func(string) loadedFile) []chan loadedFile {
+	results := make([]chan loadedFile, len(files))
+	for i := range results {
+		results[i] = make(chan loadedFile, 1)
+	}
+
+	next := make(chan int)
+	go func() {
+		defer close(next)
+		for i := range files {
+			select {
+			case next <- i:
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	for w := 0; w < jobs && w < len(files); w++ {
+		go func() {
+			for i := range next {
+				results[i] <- load(files[i])
+			}
+		}()
+	}
+	return results
+}
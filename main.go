@@ -5,12 +5,39 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"runtime"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"text/template"
+
+	"github.com/disser/mdq/mdq"
 )
 
-// parseQueryStrings splits comma-separated query strings
-func parseQueryStrings(queryStr string) []string {
-	parts := strings.Split(queryStr, ",")
+// parseQueryStrings splits comma-separated query strings. Since query
+// syntax 2, commas inside [...] index lists and {...} predicates don't
+// separate queries.
+func parseQueryStrings(queryStr string, syntax int) []string {
+	var parts []string
+	depth, start := 0, 0
+	for i, r := range queryStr {
+		switch r {
+		case '[', '{':
+			depth++
+		case ']', '}':
+			if depth > 0 {
+				depth--
+			}
+		case ',':
+			if depth == 0 || syntax < mdq.QuerySyntax2 {
+				parts = append(parts, queryStr[start:i])
+				start = i + 1
+			}
+		}
+	}
+	parts = append(parts, queryStr[start:])
+
 	var queries []string
 	for _, part := range parts {
 		trimmed := strings.TrimSpace(part)
@@ -21,12 +48,81 @@ func parseQueryStrings(queryStr string) []string {
 	return queries
 }
 
+// stringList is a flag value that can be given multiple times
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ", ")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// reportFrontmatterErrors prints how many files had unparseable frontmatter
+func reportFrontmatterErrors(count int) {
+	if count == 1 {
+		fmt.Fprintln(os.Stderr, "1 file had frontmatter errors")
+	} else {
+		fmt.Fprintf(os.Stderr, "%d files had frontmatter errors\n", count)
+	}
+}
+
+// parseQueries parses a comma-separated query string into queries
+func parseQueries(queryStr string, syntax int) ([]*mdq.Query, error) {
+	var queries []*mdq.Query
+	for _, qs := range parseQueryStrings(queryStr, syntax) {
+		query, err := mdq.ParseQuerySyntax(qs, syntax)
+		if err != nil {
+			return nil, fmt.Errorf("'%s': %v", qs, err)
+		}
+		queries = append(queries, query)
+	}
+	return queries, nil
+}
+
+// documentMatches reports whether any query matches in a document
+func documentMatches(doc *mdq.Document, queries []*mdq.Query, opts mdq.Options) bool {
+	for _, query := range queries {
+		for _, result := range mdq.ExecuteQuery(doc, query, opts) {
+			if result.Matched {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// reportMissing prints an error for each result of an explicit index that
+// matched nothing, and reports whether there were any
+func reportMissing(results []*mdq.QueryResult) bool {
+	missing := false
+	for _, result := range results {
+		if result.Missing {
+			fmt.Fprintf(os.Stderr, "Error: no match for query '%s' in %s\n", result.Query, result.File)
+			missing = true
+		}
+	}
+	return missing
+}
+
 func main() {
 	// Define command-line flags with both short and long options
 	var headOnly bool
 	flag.BoolVar(&headOnly, "h", false, "Return only the heading (the matching element)")
 	flag.BoolVar(&headOnly, "head", false, "Return only the heading (the matching element)")
 
+	var headLines int
+	flag.IntVar(&headLines, "head-lines", 0, "With -h/--head, print at most N headings")
+
+	var stripTitle bool
+	flag.BoolVar(&stripTitle, "strip-title", false, "Omit the heading line of h1 results, returning just the body")
+
+	var ignoreCase bool
+	flag.BoolVar(&ignoreCase, "i", false, "Match section titles case-insensitively")
+	flag.BoolVar(&ignoreCase, "ignore-case", false, "Match section titles case-insensitively")
+
 	var bodyOnly bool
 	flag.BoolVar(&bodyOnly, "b", false, "Return only the body (content before next section)")
 	flag.BoolVar(&bodyOnly, "body", false, "Return only the body (content before next section)")
@@ -36,8 +132,8 @@ func main() {
 	flag.BoolVar(&jsonOutput, "json", false, "Return results in JSON format")
 
 	var noBlocks bool
-	flag.BoolVar(&noBlocks, "n", false, "Omit text blocks within triple backticks")
-	flag.BoolVar(&noBlocks, "no-blocks", false, "Omit text blocks within triple backticks")
+	flag.BoolVar(&noBlocks, "n", false, "Omit fenced code blocks (between triple backticks or tildes)")
+	flag.BoolVar(&noBlocks, "no-blocks", false, "Omit fenced code blocks (between triple backticks or tildes)")
 
 	var rawOutput bool
 	flag.BoolVar(&rawOutput, "r", false, "Raw output (only the found text, no filename or query)")
@@ -47,14 +143,221 @@ func main() {
 	flag.BoolVar(&objectOutput, "o", false, "JSON object output for multiple queries (use with -j)")
 	flag.BoolVar(&objectOutput, "object", false, "JSON object output for multiple queries (use with --json)")
 
+	var jsonLines bool
+	flag.BoolVar(&jsonLines, "jsonl", false, "JSON Lines output: each result as compact JSON on its own line, including its query")
+
+	var noQueryField bool
+	flag.BoolVar(&noQueryField, "no-query-field", false, "Leave the \"query\" field out of JSON results")
+
 	var csvOutput bool
 	flag.BoolVar(&csvOutput, "c", false, "CSV output format")
 	flag.BoolVar(&csvOutput, "csv", false, "CSV output format")
 
+	var tsvOutput bool
+	flag.BoolVar(&tsvOutput, "t", false, "TSV (tab-separated) output format")
+	flag.BoolVar(&tsvOutput, "tsv", false, "TSV (tab-separated) output format")
+
+	var yamlOutput bool
+	flag.BoolVar(&yamlOutput, "y", false, "YAML output format (one mapping per file, like -j -o)")
+	flag.BoolVar(&yamlOutput, "yaml", false, "YAML output format (one mapping per file, like -j -o)")
+
+	var templateText string
+	flag.StringVar(&templateText, "template", "", "Format each result with a Go text/template, e.g. '{{.File}}: {{.Heading}}\\n'")
+
+	var templateFile string
+	flag.StringVar(&templateFile, "template-file", "", "Like --template, but read the template from FILE")
+
+	var templateOnce bool
+	flag.BoolVar(&templateOnce, "template-once", false, "Run the template once over all results (.Results, .Files, .Queries) instead of once per result")
+
+	var csvFlatten bool
+	flag.BoolVar(&csvFlatten, "csv-flatten", false, "With -c/--csv or -t/--tsv, put each value on one line, replacing newlines with spaces")
+
 	var markdownOutput bool
 	flag.BoolVar(&markdownOutput, "m", false, "Markdown output (only the sections selected by the query)")
 	flag.BoolVar(&markdownOutput, "markdown", false, "Markdown output (only the sections selected by the query)")
 
+	var htmlOutput bool
+	flag.BoolVar(&htmlOutput, "html", false, "HTML output (matched sections rendered to HTML fragments)")
+
+	var pandoc bool
+	flag.BoolVar(&pandoc, "pandoc", false, "Markdown output with frontmatter as a Pandoc title block (% title/% author/% date)")
+
+	var footnotes bool
+	flag.BoolVar(&footnotes, "footnotes", false, "Output the footnotes ([^label] and their text) of matched sections")
+
+	var quotes bool
+	flag.BoolVar(&quotes, "quotes", false, "Output the blockquotes of matched sections, with > markers removed")
+	var links bool
+	flag.BoolVar(&links, "links", false, "Output the links of matched sections: text as heading, URL as body")
+	var images bool
+	flag.BoolVar(&images, "images", false, "Output the images of matched sections: alt text as heading, source as body")
+
+	var hash bool
+	flag.BoolVar(&hash, "hash", false, "Output a SHA-256 hash of each matched section's normalized body instead of the body")
+
+	var minBodyLines, maxBodyLines int
+	flag.IntVar(&minBodyLines, "min-body-lines", 0, "Only match sections whose body has at least N lines")
+	flag.IntVar(&maxBodyLines, "max-body-lines", 0, "Only match sections whose body has at most N lines")
+
+	var plain, plainURLs bool
+	flag.BoolVar(&plain, "plain", false, "Strip inline markdown formatting (emphasis, links, inline code) from bodies")
+	flag.BoolVar(&plainURLs, "plain-urls", false, "With --plain, keep link URLs in parentheses after the link text")
+
+	var onlyBlocks bool
+	flag.BoolVar(&onlyBlocks, "only-blocks", false, "Keep only the contents of fenced code blocks in bodies (the opposite of -n)")
+
+	var keepFences bool
+	flag.BoolVar(&keepFences, "keep-fences", false, "With --only-blocks, keep the fence lines around each block")
+
+	var lang string
+	flag.StringVar(&lang, "lang", "", "With --only-blocks, keep only code blocks in this language ('' for blocks with none); implies --only-blocks")
+
+	var nested bool
+	flag.BoolVar(&nested, "nested", false, "Include subsections in section bodies (up to the next heading of the same or a higher level)")
+
+	var frontmatterFrom string
+	flag.StringVar(&frontmatterFrom, "frontmatter-from", "", "Load default frontmatter from a YAML `FILE`; each document's own fields take precedence")
+
+	var lines bool
+	flag.BoolVar(&lines, "lines", false, "Output the start-end source line range of each matched section instead of its body")
+
+	var count bool
+	flag.BoolVar(&count, "C", false, "Output the number of matches of each query instead of the matches")
+	flag.BoolVar(&count, "count", false, "Output the number of matches of each query instead of the matches")
+
+	var table bool
+	flag.BoolVar(&table, "table", false, "Output the pipe tables in matched sections as CSV, or as JSON rows keyed by header with -j")
+
+	var standalone bool
+	flag.BoolVar(&standalone, "standalone", false, "Output each matched section as a complete markdown document with the file's frontmatter and the heading promoted to h1")
+
+	var querySyntax int
+	flag.IntVar(&querySyntax, "query-syntax", mdq.QuerySyntaxLatest, "Query syntax version: 1 for literal titles and [N] only, 2 for anchors, predicates, and index lists")
+
+	var stream bool
+	flag.BoolVar(&stream, "stream", false, "With -j, write the JSON array incrementally as each file is processed")
+
+	var firstMatchOnly bool
+	flag.BoolVar(&firstMatchOnly, "first-match-only", false, "Report only the first file (in argument order, or --sort-by order) that any query matches")
+
+	var verbose bool
+	flag.BoolVar(&verbose, "V", false, "Log to stderr which sections and fields each query matched or rejected, and why")
+	flag.BoolVar(&verbose, "verbose", false, "Log to stderr which sections and fields each query matched or rejected, and why")
+
+	var exitZero bool
+	flag.BoolVar(&exitZero, "exit-zero", false, "Exit with status 0 even when no query matches anything")
+
+	var errorOnMissing bool
+	flag.BoolVar(&errorOnMissing, "error-on-missing", false, "Exit with an error when an explicit index like ##[9] matches nothing")
+
+	var jsonKeys string
+	flag.StringVar(&jsonKeys, "json-keys", "query", "Keys for JSON object output: query, title (section title), or field (frontmatter field name)")
+
+	var withFormat bool
+	flag.BoolVar(&withFormat, "with-format", false, "Include the frontmatter format in JSON object output (use with -j -o)")
+
+	var verbatim bool
+	flag.BoolVar(&verbatim, "verbatim", false, "Raw output of each section's heading and body exactly as parsed (ignores -h/-b)")
+
+	var dateFormat string
+	flag.StringVar(&dateFormat, "date-format", "", "Go time layout for date values, e.g. 2006-01-02 (default RFC 3339 for mtime)")
+
+	var headingSep string
+	flag.StringVar(&headingSep, "heading-sep", "", "Separator between heading and body, with \\n and \\t escapes (default newline; blank line in markdown)")
+
+	var frontmatterOnly bool
+	flag.BoolVar(&frontmatterOnly, "frontmatter-only", false, "Only output results of frontmatter queries")
+
+	var sectionsOnly bool
+	flag.BoolVar(&sectionsOnly, "sections-only", false, "Only output results of section queries")
+
+	var squeezeBlank bool
+	flag.BoolVar(&squeezeBlank, "squeeze-blank", false, "Collapse runs of blank lines in output bodies into one")
+
+	var oneLine bool
+	flag.BoolVar(&oneLine, "oneline", false, "Print each result on one line, escaping newlines as \\n")
+
+	var defaultValue string
+	flag.StringVar(&defaultValue, "default", "", "Output VALUE for any file/query pair with no match")
+
+	var includeEmpty bool
+	flag.BoolVar(&includeEmpty, "include-empty", false, "Keep empty results (e.g. body-only output of empty sections) in text and markdown output")
+
+	var sortBy string
+	flag.StringVar(&sortBy, "sort-by", "", "Order files by a frontmatter field before output (frontmatter:FIELD[:asc|:desc])")
+
+	var manifestPath string
+	flag.StringVar(&manifestPath, "manifest", "", "Write a JSON manifest of processed files (status, match count, frontmatter format) to FILE")
+
+	var tocJSON bool
+	flag.BoolVar(&tocJSON, "toc-json", false, "Output the heading hierarchy of FILES as nested JSON (no QUERY)")
+
+	var tocDepth int
+	flag.IntVar(&tocDepth, "toc-depth", 0, "Deepest heading level to include in table of contents output (0 for all)")
+
+	var splitDoc bool
+	flag.BoolVar(&splitDoc, "split-doc", false, "Output each file as JSON {frontmatter, body} (no QUERY)")
+
+	var convertFormat string
+	flag.StringVar(&convertFormat, "convert-frontmatter", "", "Output each file's frontmatter re-serialized as yaml, toml, or json (no QUERY; with --split-doc, the whole document)")
+
+	var emptySections bool
+	flag.BoolVar(&emptySections, "empty-sections", false, "Report headings with no content beneath them (no QUERY)")
+
+	var requireFields string
+	flag.StringVar(&requireFields, "require", "", "Report files whose frontmatter lacks a value for any of these comma-separated fields, and exit with status 1 (no QUERY)")
+
+	var duplicates bool
+	flag.BoolVar(&duplicates, "duplicates", false, "Report headings that share a title within a file (no QUERY)")
+
+	var where stringList
+	flag.Var(&where, "where", "Only query files whose frontmatter matches FIELD=VALUE or FIELD!=VALUE (repeatable)")
+
+	var fold bool
+	flag.BoolVar(&fold, "fold", false, "Make --where comparisons ignore case and accents")
+
+	var since string
+	flag.StringVar(&since, "since", "", "Only query files whose date field is on or after this date")
+
+	var until string
+	flag.StringVar(&until, "until", "", "Only query files whose date field is on or before this date")
+
+	var dateField string
+	flag.StringVar(&dateField, "date-field", "date", "Frontmatter field used by --since and --until")
+
+	var filesFrom string
+	flag.StringVar(&filesFrom, "files-from", "", "Also query the newline-separated file paths listed in FILE (- for stdin)")
+
+	var recursive bool
+	flag.BoolVar(&recursive, "R", false, "Query the markdown files in directories given as FILES, recursively")
+	flag.BoolVar(&recursive, "recursive", false, "Query the markdown files in directories given as FILES, recursively")
+
+	var extensions string
+	flag.StringVar(&extensions, "extensions", ".md,.markdown", "Comma-separated file extensions that -R/--recursive treats as markdown")
+
+	var sortFiles bool
+	flag.BoolVar(&sortFiles, "sort-files", false, "Query files in alphabetical order of path instead of the order given")
+
+	var jobs int
+	flag.IntVar(&jobs, "jobs", runtime.GOMAXPROCS(0), "Read and parse up to N files at once (output stays in file order)")
+
+	var maxDepth int
+	flag.IntVar(&maxDepth, "max-depth", 64, "Discard frontmatter nested deeper than N levels (0 for no limit)")
+
+	var quiet bool
+	flag.BoolVar(&quiet, "q", false, "Don't report the number of files with frontmatter errors")
+	flag.BoolVar(&quiet, "quiet", false, "Don't report the number of files with frontmatter errors")
+
+	var get bool
+	flag.BoolVar(&get, "get", false, "Print exactly one value with no decoration; requires one file and one query")
+
+	var copyOutput bool
+	flag.BoolVar(&copyOutput, "copy", false, "Copy the output to the system clipboard instead of printing it")
+
+	var repl bool
+	flag.BoolVar(&repl, "repl", false, "Parse FILES once, then read queries from stdin line by line")
+
 	flag.Usage = func() {
 		fmt.Fprintf(os.Stderr, "Usage: mdq [-h|--head|-b|--body] [-j|--json] [-n|--no-blocks] QUERY [FILES...]\n\n")
 		fmt.Fprintf(os.Stderr, "Query markdown files and extract information like 'jq' does for JSON.\n\n")
@@ -64,20 +367,87 @@ func main() {
 		fmt.Fprintf(os.Stderr, "  ##Notes     All h2 blocks titled \"Notes\"\n")
 		fmt.Fprintf(os.Stderr, "  ##Notes[1]  Second h2 block titled \"Notes\"\n")
 		fmt.Fprintf(os.Stderr, "  ##[3]       Fourth h2 in the document (0-indexed)\n")
-		fmt.Fprintf(os.Stderr, "  date        \"date\" field from YAML frontmatter\n\n")
+		fmt.Fprintf(os.Stderr, "  ##^Intro    All h2 blocks whose title starts with \"Intro\"\n")
+		fmt.Fprintf(os.Stderr, "  ##tes$      All h2 blocks whose title ends with \"tes\"\n")
+		fmt.Fprintf(os.Stderr, "  ##?/TODO/   All h2 blocks whose body matches /TODO/\n")
+		fmt.Fprintf(os.Stderr, "  ##Tasks/-   Each list item in the h2 block titled \"Tasks\"\n")
+		fmt.Fprintf(os.Stderr, "  #{has=##Notes}  All h1 blocks containing an h2 titled \"Notes\"\n")
+		fmt.Fprintf(os.Stderr, "  \"##A > ###B\"  All h3 blocks titled \"B\" under the h2 titled \"A\"\n")
+		fmt.Fprintf(os.Stderr, "  date        \"date\" field from YAML frontmatter\n")
+		fmt.Fprintf(os.Stderr, "  mtime       File modification time (unless frontmatter has \"mtime\")\n\n")
 		fmt.Fprintf(os.Stderr, "Options:\n")
 		flag.PrintDefaults()
 		fmt.Fprintf(os.Stderr, "\nIf no FILES are provided, reads from stdin.\n")
+		fmt.Fprintf(os.Stderr, "Default flags can be set with MDQ_OPTS, MDQ_FORMAT, and MDQ_NO_BLOCKS.\n")
+		fmt.Fprintf(os.Stderr, "With --repl, all arguments are FILES and queries are read from stdin.\n")
+		fmt.Fprintf(os.Stderr, "With --toc-json, --duplicates, --empty-sections, --require, --split-doc, or --convert-frontmatter, all arguments are FILES.\n")
+	}
+
+	// Apply defaults from the environment before the command line
+	defaults, err := envArgs()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
 	}
+	flag.CommandLine.Parse(defaults)
+	if flag.NArg() > 0 {
+		fmt.Fprintln(os.Stderr, "Error: MDQ_OPTS may only contain flags, not QUERY or FILES")
+		os.Exit(1)
+	}
+
+	// Mutually exclusive flags given on the command line replace the
+	// environment's choice rather than conflicting with it
+	envHeadOnly, envBodyOnly := headOnly, bodyOnly
+	envJSON, envJSONLines, envCSV, envTSV, envYAML, envMarkdown, envHTML := jsonOutput, jsonLines, csvOutput, tsvOutput, yamlOutput, markdownOutput, htmlOutput
+	headOnly, bodyOnly = false, false
+	jsonOutput, jsonLines, csvOutput, tsvOutput, yamlOutput, markdownOutput, htmlOutput = false, false, false, false, false, false, false
 
 	flag.Parse()
 
+	if !headOnly && !bodyOnly {
+		headOnly, bodyOnly = envHeadOnly, envBodyOnly
+	}
+	if !jsonOutput && !jsonLines && !csvOutput && !tsvOutput && !yamlOutput && !markdownOutput && !htmlOutput {
+		jsonOutput, jsonLines, csvOutput, tsvOutput, yamlOutput, markdownOutput, htmlOutput = envJSON, envJSONLines, envCSV, envTSV, envYAML, envMarkdown, envHTML
+	}
+
 	// Check for conflicting flags
 	if headOnly && bodyOnly {
 		fmt.Fprintln(os.Stderr, "Error: -h/--head and -b/--body flags are mutually exclusive")
 		os.Exit(1)
 	}
 
+	// Filtering code blocks by language implies keeping only code blocks
+	langSet := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == "lang" {
+			langSet = true
+		}
+	})
+	if langSet {
+		onlyBlocks = true
+	}
+
+	if noBlocks && onlyBlocks {
+		fmt.Fprintln(os.Stderr, "Error: -n/--no-blocks cannot be used with --only-blocks or --lang")
+		os.Exit(1)
+	}
+
+	if frontmatterOnly && sectionsOnly {
+		fmt.Fprintln(os.Stderr, "Error: --frontmatter-only and --sections-only flags are mutually exclusive")
+		os.Exit(1)
+	}
+
+	// Pandoc output is a flavor of markdown output
+	if pandoc {
+		markdownOutput = true
+	}
+
+	// JSON Lines output is a flavor of JSON output
+	if jsonLines {
+		jsonOutput = true
+	}
+
 	// Check for conflicting output formats
 	outputFlags := 0
 	if jsonOutput {
@@ -86,96 +456,630 @@ func main() {
 	if csvOutput {
 		outputFlags++
 	}
+	if tsvOutput {
+		outputFlags++
+	}
+	if yamlOutput {
+		outputFlags++
+	}
 	if markdownOutput {
 		outputFlags++
 	}
+	if htmlOutput {
+		outputFlags++
+	}
 	if outputFlags > 1 {
-		fmt.Fprintln(os.Stderr, "Error: -j/--json, -c/--csv, and -m/--markdown flags are mutually exclusive")
+		fmt.Fprintln(os.Stderr, "Error: -j/--json, -c/--csv, -t/--tsv, -y/--yaml, -m/--markdown, and --html flags are mutually exclusive")
+		os.Exit(1)
+	}
+	if stream && (!jsonOutput || objectOutput || sortBy != "" || firstMatchOnly || get || copyOutput || standalone) {
+		fmt.Fprintln(os.Stderr, "Error: --stream requires -j or --jsonl and cannot be used with -o, --sort-by, --first-match-only, --get, --copy, or --standalone")
+		os.Exit(1)
+	}
+	if filesFrom == "-" && repl {
+		fmt.Fprintln(os.Stderr, "Error: --files-from - cannot be used with --repl, which reads queries from stdin")
+		os.Exit(1)
+	}
+	if count && (stream || standalone || get || markdownOutput || yamlOutput || htmlOutput) {
+		fmt.Fprintln(os.Stderr, "Error: -C/--count cannot be used with --stream, --standalone, --get, -m, -y, or --html")
+		os.Exit(1)
+	}
+	if table && (count || stream || standalone || get || headOnly || markdownOutput || yamlOutput || htmlOutput) {
+		fmt.Fprintln(os.Stderr, "Error: --table cannot be used with -C, --stream, --standalone, --get, -h, -m, -y, or --html")
+		os.Exit(1)
+	}
+	if standalone && (headOnly || bodyOnly || jsonOutput || csvOutput || tsvOutput || yamlOutput || htmlOutput) {
+		fmt.Fprintln(os.Stderr, "Error: --standalone cannot be used with -h, -b, -j, -c, -t, -y, or --html")
+		os.Exit(1)
+	}
+
+	if templateOnce && templateText == "" && templateFile == "" {
+		fmt.Fprintln(os.Stderr, "Error: --template-once requires --template or --template-file")
 		os.Exit(1)
 	}
 
+	// Compile the output template before any file is read
+	var outputTemplate *template.Template
+	if templateText != "" || templateFile != "" {
+		if templateText != "" && templateFile != "" {
+			fmt.Fprintln(os.Stderr, "Error: --template and --template-file are mutually exclusive")
+			os.Exit(1)
+		}
+		if outputFlags > 0 || standalone || count || table || stream {
+			fmt.Fprintln(os.Stderr, "Error: --template cannot be used with -j, -c, -t, -y, -m, --html, --standalone, -C, --table, or --stream")
+			os.Exit(1)
+		}
+
+		text := templateText
+		if templateFile != "" {
+			content, err := os.ReadFile(templateFile)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error reading template: %v\n", err)
+				os.Exit(1)
+			}
+			text = string(content)
+		} else if unquoted, err := strconv.Unquote(`"` + text + `"`); err == nil {
+			// Interpret escape sequences like \n on the command line
+			text = unquoted
+		}
+
+		var err error
+		outputTemplate, err = mdq.ParseTemplate(text)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error parsing template: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
 	// Get query and files
 	args := flag.Args()
-	if len(args) < 1 {
-		flag.Usage()
+	var queryStr string
+	var files []string
+	if repl {
+		// In REPL mode queries come from stdin, so every argument is a file
+		if len(args) < 1 {
+			fmt.Fprintln(os.Stderr, "Error: --repl requires at least one file")
+			os.Exit(1)
+		}
+		files = args
+	} else if tocJSON || duplicates || emptySections || requireFields != "" || splitDoc || convertFormat != "" {
+		// Structural reports cover whole documents, so there is no query
+		files = args
+	} else {
+		if len(args) < 1 {
+			flag.Usage()
+			os.Exit(1)
+		}
+		queryStr = args[0]
+		files = args[1:]
+	}
+
+	// Add the files listed in --files-from after those given as arguments
+	if filesFrom != "" {
+		listed, err := readFileList(filesFrom)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading file list: %v\n", err)
+			os.Exit(1)
+		}
+		files = append(files, listed...)
+	}
+
+	// Read from stdin only when no FILES were given at all
+	readStdin := len(files) == 0 && filesFrom == ""
+
+	// Expand glob patterns the shell didn't expand for us
+	files = expandFileArgs(files)
+
+	// Replace directories with the markdown files beneath them
+	if recursive {
+		var exts []string
+		for _, ext := range strings.Split(extensions, ",") {
+			if ext = strings.TrimSpace(ext); ext != "" {
+				if !strings.HasPrefix(ext, ".") {
+					ext = "." + ext
+				}
+				exts = append(exts, ext)
+			}
+		}
+		files = expandDirectories(files, exts)
+	}
+
+	// Files are queried in the order given unless asked otherwise
+	if sortFiles {
+		sort.Strings(files)
+	}
+
+	// --get is for a single value from a single input
+	if get && len(files) > 1 {
+		fmt.Fprintln(os.Stderr, "Error: --get requires exactly one file")
 		os.Exit(1)
 	}
 
-	queryStr := args[0]
-	files := args[1:]
+	// Interpret escape sequences in the heading separator
+	if headingSep != "" {
+		if unquoted, err := strconv.Unquote(`"` + headingSep + `"`); err == nil {
+			headingSep = unquoted
+		}
+	}
 
-	// Parse comma-separated queries
-	queryStrings := parseQueryStrings(queryStr)
-	var queries []*Query
-	for _, qs := range queryStrings {
-		query, err := ParseQuery(qs)
+	if querySyntax < mdq.QuerySyntax1 || querySyntax > mdq.QuerySyntaxLatest {
+		fmt.Fprintf(os.Stderr, "Error: --query-syntax must be between %d and %d, got %d\n", mdq.QuerySyntax1, mdq.QuerySyntaxLatest, querySyntax)
+		os.Exit(1)
+	}
+	if minBodyLines < 0 || maxBodyLines < 0 || (maxBodyLines > 0 && minBodyLines > maxBodyLines) {
+		fmt.Fprintf(os.Stderr, "Error: invalid body line range %d-%d\n", minBodyLines, maxBodyLines)
+		os.Exit(1)
+	}
+	if jobs < 1 {
+		fmt.Fprintf(os.Stderr, "Error: --jobs must be at least 1, got %d\n", jobs)
+		os.Exit(1)
+	}
+	if convertFormat != "" && !mdq.IsFrontmatterFormat(convertFormat) {
+		fmt.Fprintf(os.Stderr, "Error: --convert-frontmatter must be yaml, toml, or json, got %q\n", convertFormat)
+		os.Exit(1)
+	}
+	var required []*mdq.Query
+	if requireFields != "" {
+		var err error
+		required, err = mdq.ParseRequiredFields(requireFields)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error parsing query '%s': %v\n", qs, err)
+			fmt.Fprintf(os.Stderr, "Error: --require: %v\n", err)
 			os.Exit(1)
 		}
-		queries = append(queries, query)
+	}
+	if jsonKeys != "query" && jsonKeys != "title" && jsonKeys != "field" {
+		fmt.Fprintf(os.Stderr, "Error: --json-keys must be query, title, or field, got %q\n", jsonKeys)
+		os.Exit(1)
 	}
 
-	// Set up options
-	opts := Options{
-		HeadOnly:       headOnly,
-		BodyOnly:       bodyOnly,
-		JSONOutput:     jsonOutput,
-		NoBlocks:       noBlocks,
-		RawOutput:      rawOutput,
-		ObjectOutput:   objectOutput,
-		CSVOutput:      csvOutput,
-		MarkdownOutput: markdownOutput,
+	// Parse the sort specification
+	var sortSpec *mdq.SortSpec
+	if sortBy != "" {
+		var err error
+		sortSpec, err = mdq.ParseSortSpec(sortBy)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error parsing --sort-by: %v\n", err)
+			os.Exit(1)
+		}
 	}
 
-	var results []*QueryResult
+	// Parse frontmatter predicates
+	var predicates []*mdq.Predicate
+	for _, expr := range where {
+		predicate, err := mdq.ParsePredicate(expr)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error parsing --where: %v\n", err)
+			os.Exit(1)
+		}
+		predicates = append(predicates, predicate)
+	}
 
-	// Process files or stdin
-	if len(files) == 0 {
-		// Read from stdin
-		content, err := io.ReadAll(os.Stdin)
+	// Parse the date range
+	var dateRange *mdq.DateRange
+	if since != "" || until != "" {
+		var err error
+		dateRange, err = mdq.ParseDateRange(dateField, since, until)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error reading stdin: %v\n", err)
+			fmt.Fprintf(os.Stderr, "Error parsing %v\n", err)
 			os.Exit(1)
 		}
+	}
 
-		doc, err := ParseDocument(string(content), "stdin", noBlocks)
+	// Parse comma-separated queries
+	var queries []*mdq.Query
+	if !repl && !tocJSON && !duplicates && !emptySections && requireFields == "" && !splitDoc && convertFormat == "" {
+		var err error
+		queries, err = parseQueries(queryStr, querySyntax)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error parsing query %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	if get && len(queries) != 1 {
+		fmt.Fprintln(os.Stderr, "Error: --get requires exactly one query")
+		os.Exit(1)
+	}
+
+	// Set up options
+	opts := mdq.Options{
+		HeadOnly:        headOnly,
+		BodyOnly:        bodyOnly,
+		JSONOutput:      jsonOutput,
+		JSONLines:       jsonLines,
+		NoQueryField:    noQueryField,
+		NoBlocks:        noBlocks,
+		RawOutput:       rawOutput,
+		ObjectOutput:    objectOutput,
+		CSVOutput:       csvOutput,
+		TSVOutput:       tsvOutput,
+		YAMLOutput:      yamlOutput,
+		CSVFlatten:      csvFlatten,
+		MarkdownOutput:  markdownOutput,
+		HTMLOutput:      htmlOutput,
+		WithFormat:      withFormat,
+		Verbatim:        verbatim,
+		IncludeEmpty:    includeEmpty,
+		OneLine:         oneLine,
+		FrontmatterOnly: frontmatterOnly,
+		SectionsOnly:    sectionsOnly,
+		HeadingSep:      headingSep,
+		Pandoc:          pandoc,
+		HeadLines:       headLines,
+		DateFormat:      dateFormat,
+		StripTitle:      stripTitle,
+		Footnotes:       footnotes,
+		SqueezeBlank:    squeezeBlank,
+		Quotes:          quotes,
+		Links:           links,
+		Images:          images,
+		Default:         defaultValue,
+		JSONKeys:        jsonKeys,
+		Hash:            hash,
+		MinBodyLines:    minBodyLines,
+		MaxBodyLines:    maxBodyLines,
+		Verbose:         verbose,
+		Lines:           lines,
+		Plain:           plain || plainURLs,
+		PlainURLs:       plainURLs,
+		QuerySyntax:     querySyntax,
+		IgnoreCase:      ignoreCase,
+		Count:           count,
+		TemplateOnce:    templateOnce,
+	}
+
+	// Load shared default frontmatter
+	var frontmatterDefaults map[string]interface{}
+	if frontmatterFrom != "" {
+		var err error
+		frontmatterDefaults, err = mdq.LoadFrontmatterFile(frontmatterFrom)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error parsing stdin: %v\n", err)
+			fmt.Fprintf(os.Stderr, "Error reading --frontmatter-from: %v\n", err)
 			os.Exit(1)
 		}
+	}
+
+	var docs []*mdq.Document
+	var manifest []*mdq.ManifestEntry
+
+	// With --first-match-only, the first matching file ends the file loop
+	// unless documents must be sorted first
+	firstMatchOnly = firstMatchOnly && len(queries) > 0
+	var firstMatch *mdq.Document
 
-		// Execute all queries against the document
-		for _, query := range queries {
-			queryResults := ExecuteQuery(doc, query, opts)
-			results = append(results, queryResults...)
+	// With --stream, each file's results are written as soon as it is read
+	// instead of keeping the document
+	var jsonStream *mdq.JSONStream
+	if stream && len(queries) > 0 {
+		jsonStream = mdq.NewJSONStream(os.Stdout)
+		if jsonLines {
+			jsonStream = mdq.NewJSONLinesStream(os.Stdout)
+		}
+	}
+	// Files whose frontmatter couldn't be parsed, reported at exit. Every
+	// loaded file counts, even ones --where or the date range skip.
+	frontmatterErrors := 0
+	streamedMissing := false
+	streamedMatch := false
+
+	loading := loadOptions{
+		noBlocks:   noBlocks,
+		onlyBlocks: onlyBlocks,
+		codeBlocks: mdq.CodeBlockOptions{KeepFences: keepFences, FilterLang: langSet, Lang: lang},
+		nested:     nested,
+		defaults:   frontmatterDefaults,
+		maxDepth:   maxDepth,
+	}
+
+	// Process files or stdin
+	if readStdin {
+		// Parse stdin as it is read
+		doc, err := mdq.ParseDocumentReader(os.Stdin, "stdin", noBlocks)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading stdin: %v\n", err)
+			os.Exit(1)
+		}
+		prepareDocument(doc, loading)
+		if doc.FrontmatterError != nil {
+			frontmatterErrors++
+		}
+		if mdq.MatchesAll(doc, predicates, fold) && (dateRange == nil || dateRange.Contains(doc)) {
+			docs = append(docs, doc)
+			manifest = append(manifest, &mdq.ManifestEntry{File: "stdin", Status: mdq.ManifestOK, FrontmatterFormat: doc.FrontmatterFormat})
+		} else {
+			manifest = append(manifest, &mdq.ManifestEntry{File: "stdin", Status: mdq.ManifestSkipped, FrontmatterFormat: doc.FrontmatterFormat})
 		}
 	} else {
+		// Load files concurrently, but handle them in argument order
+		stop := make(chan struct{})
+		stopLoading := sync.OnceFunc(func() { close(stop) })
+		defer stopLoading()
+		loaded := loadFiles(files, jobs, stop, func(filePath string) loadedFile {
+			return loadFile(filePath, loading)
+		})
+
 		// Process each file
-		for _, filePath := range files {
-			content, err := os.ReadFile(filePath)
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "Error reading %s: %v\n", filePath, err)
+		for i, filePath := range files {
+			result := <-loaded[i]
+			if result.readErr != nil {
+				fmt.Fprintf(os.Stderr, "Error reading %s: %v\n", filePath, result.readErr)
+				manifest = append(manifest, &mdq.ManifestEntry{File: filePath, Status: mdq.ManifestSkipped, Error: result.readErr.Error()})
+				continue
+			}
+			if result.err != nil {
+				fmt.Fprintf(os.Stderr, "Error parsing %s: %v\n", filePath, result.err)
+				manifest = append(manifest, &mdq.ManifestEntry{File: filePath, Status: mdq.ManifestParseError, Error: result.err.Error()})
 				continue
 			}
+			doc := result.doc
+			if doc.FrontmatterError != nil {
+				frontmatterErrors++
+			}
 
-			doc, err := ParseDocument(string(content), filePath, noBlocks)
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "Error parsing %s: %v\n", filePath, err)
+			// Skip files whose frontmatter doesn't match --where or the date range
+			if !mdq.MatchesAll(doc, predicates, fold) || (dateRange != nil && !dateRange.Contains(doc)) {
+				manifest = append(manifest, &mdq.ManifestEntry{File: filePath, Status: mdq.ManifestSkipped, FrontmatterFormat: doc.FrontmatterFormat})
 				continue
 			}
 
-			// Execute all queries against the document
-			for _, query := range queries {
-				queryResults := ExecuteQuery(doc, query, opts)
-				results = append(results, queryResults...)
+			entry := &mdq.ManifestEntry{File: filePath, Status: mdq.ManifestOK, FrontmatterFormat: doc.FrontmatterFormat}
+			if doc.FrontmatterError != nil {
+				// The file is still queried, but its frontmatter was lost
+				entry.Status = mdq.ManifestParseError
+				entry.Error = doc.FrontmatterError.Error()
+			}
+			manifest = append(manifest, entry)
+
+			if jsonStream != nil {
+				results := mdq.ExecuteQueries([]*mdq.Document{doc}, queries, opts)
+				mdq.CountMatches([]*mdq.ManifestEntry{entry}, results)
+				if errorOnMissing && reportMissing(results) {
+					streamedMissing = true
+				}
+				if mdq.HasMatch(results) {
+					streamedMatch = true
+				}
+				if err := jsonStream.Write(mdq.PrepareResults(results, opts)); err != nil {
+					fmt.Fprintf(os.Stderr, "Error writing output: %v\n", err)
+					os.Exit(1)
+				}
+				continue
+			}
+			docs = append(docs, doc)
+
+			if firstMatchOnly && sortSpec == nil && documentMatches(doc, queries, opts) {
+				// Files after this one are no longer needed
+				firstMatch = doc
+				stopLoading()
+				break
+			}
+		}
+	}
+
+	// Order documents so results come out sorted
+	if sortSpec != nil {
+		mdq.SortDocuments(docs, sortSpec)
+	}
+
+	// Keep only the first document with a match
+	if firstMatchOnly {
+		if sortSpec != nil || readStdin {
+			for _, doc := range docs {
+				if documentMatches(doc, queries, opts) {
+					firstMatch = doc
+					break
+				}
+			}
+		}
+		docs = nil
+		if firstMatch != nil {
+			docs = []*mdq.Document{firstMatch}
+		}
+	}
+
+	if frontmatterErrors > 0 && !quiet {
+		defer reportFrontmatterErrors(frontmatterErrors)
+	}
+
+	// Table of contents mode outputs the structure of the documents themselves
+	if tocJSON {
+		if output := mdq.FormatTOCJSON(docs, tocDepth); output != "" {
+			fmt.Println(output)
+		}
+		return
+	}
+
+	// Frontmatter conversion, optionally passing the body through
+	if convertFormat != "" {
+		output, err := mdq.FormatConverted(docs, convertFormat, splitDoc)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error converting frontmatter: %v\n", err)
+			os.Exit(1)
+		}
+		if output != "" {
+			fmt.Println(output)
+		}
+		return
+	}
+
+	// Whole-document frontmatter/body split
+	if splitDoc {
+		if output := mdq.FormatSplitJSON(docs); output != "" {
+			fmt.Println(output)
+		}
+		return
+	}
+
+	// Duplicate heading report
+	if duplicates {
+		if output := mdq.FormatDuplicates(docs, opts); output != "" {
+			fmt.Println(output)
+		}
+		return
+	}
+
+	// Required frontmatter check, failing if any file falls short
+	if required != nil {
+		if missing := mdq.CheckRequiredFields(docs, required); len(missing) > 0 {
+			fmt.Fprintln(os.Stderr, mdq.FormatMissingFields(missing))
+			if frontmatterErrors > 0 && !quiet {
+				reportFrontmatterErrors(frontmatterErrors)
 			}
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Empty section report
+	if emptySections {
+		if output := mdq.FormatEmptySections(docs, opts); output != "" {
+			fmt.Println(output)
 		}
+		return
+	}
+
+	// In REPL mode, answer queries from stdin against the parsed documents
+	if repl {
+		runREPL(docs, opts, os.Stdin, os.Stdout)
+		return
+	}
+
+	// Finish the stream with any documents read from stdin
+	if jsonStream != nil {
+		results := mdq.ExecuteQueries(docs, queries, opts)
+		mdq.CountMatches(manifest[len(manifest)-len(docs):], results)
+		if errorOnMissing && reportMissing(results) {
+			streamedMissing = true
+		}
+		if mdq.HasMatch(results) {
+			streamedMatch = true
+		}
+		err := jsonStream.Write(mdq.PrepareResults(results, opts))
+		if err == nil {
+			err = jsonStream.Close()
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing output: %v\n", err)
+			os.Exit(1)
+		}
+
+		if manifestPath != "" {
+			if err := mdq.WriteManifest(manifestPath, manifest); err != nil {
+				fmt.Fprintf(os.Stderr, "Error writing manifest: %v\n", err)
+			}
+		}
+		if streamedMissing || (!streamedMatch && !exitZero) {
+			if frontmatterErrors > 0 && !quiet {
+				reportFrontmatterErrors(frontmatterErrors)
+			}
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Execute all queries against the documents
+	results := mdq.ExecuteQueries(docs, queries, opts)
+
+	// Record what happened to each file
+	if manifestPath != "" {
+		mdq.CountMatches(manifest, results)
+		if err := mdq.WriteManifest(manifestPath, manifest); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing manifest: %v\n", err)
+		}
+	}
+
+	// Fail when a section the caller indexed explicitly doesn't exist
+	if errorOnMissing {
+		if reportMissing(results) {
+			if frontmatterErrors > 0 && !quiet {
+				reportFrontmatterErrors(frontmatterErrors)
+			}
+			os.Exit(1)
+		}
+	}
+
+	// Print the single value, failing unless exactly one result matched;
+	// a match with an empty value still counts
+	if get {
+		var matched []*mdq.QueryResult
+		for _, result := range results {
+			if result.Matched {
+				matched = append(matched, result)
+			}
+		}
+		if len(matched) != 1 {
+			fmt.Fprintf(os.Stderr, "Error: --get expected exactly one match for '%s', found %d\n", queryStr, len(matched))
+			os.Exit(1)
+		}
+		if headOnly {
+			fmt.Print(matched[0].Heading)
+		} else {
+			fmt.Print(matched[0].Body)
+		}
+		return
 	}
 
 	// Format and print output
-	output := FormatOutput(results, opts)
-	if output != "" {
-		fmt.Println(output)
+	var output string
+	matched := mdq.HasMatch(results)
+	if standalone {
+		var err error
+		output, err = mdq.FormatStandalone(docs, queries, results)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error formatting standalone output: %v\n", err)
+			os.Exit(1)
+		}
+	} else if outputTemplate != nil {
+		// Template output goes straight to stdout as it is executed,
+		// unless it's headed for the clipboard
+		var buf strings.Builder
+		var w io.Writer = os.Stdout
+		if copyOutput {
+			w = &buf
+		}
+		if err := mdq.ExecuteTemplate(w, outputTemplate, results, opts); err != nil {
+			fmt.Fprintf(os.Stderr, "Error executing template: %v\n", err)
+			os.Exit(1)
+		}
+		output = buf.String()
+	} else if count {
+		// The queries already ran above, so don't log them a second time
+		countOpts := opts
+		countOpts.Verbose = false
+		output = mdq.FormatCounts(mdq.CountQueries(docs, queries, countOpts), opts)
+	} else if table {
+		// Only sections with a table count as a match
+		tables := mdq.ExtractTables(mdq.PrepareResults(results, opts))
+		output = mdq.FormatTables(tables, opts)
+		matched = len(tables) > 0
+	} else {
+		output = mdq.FormatOutput(results, opts)
+	}
+
+	// Copy to the clipboard instead of printing, if possible
+	copied := false
+	if copyOutput {
+		if err := copyToClipboard(output); err == nil {
+			copied = true
+		} else {
+			fmt.Fprintf(os.Stderr, "Warning: couldn't copy to clipboard (%v), printing instead\n", err)
+		}
+	}
+
+	if output != "" && !copied {
+		if outputTemplate != nil {
+			// The template decides where lines end
+			fmt.Print(output)
+		} else {
+			fmt.Println(output)
+		}
+	}
+
+	// Like grep, fail when no query matched anything
+	if !exitZero && !matched {
+		if frontmatterErrors > 0 && !quiet {
+			reportFrontmatterErrors(frontmatterErrors)
+		}
+		os.Exit(1)
 	}
 }
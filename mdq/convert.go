@@ -0,0 +1,95 @@
+package mdq
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// frontmatterFences are the delimiters written around each frontmatter
+// format in a full document. JSON frontmatter is a bare object.
+var frontmatterFences = map[string]string{
+	"yaml": "---",
+	"toml": "+++",
+	"json": "",
+}
+
+// IsFrontmatterFormat reports whether frontmatter can be serialized in the
+// named format
+func IsFrontmatterFormat(format string) bool {
+	_, ok := frontmatterFences[format]
+	return ok
+}
+
+// serializeFrontmatter serializes a frontmatter map in the given format
+func serializeFrontmatter(frontmatter map[string]interface{}, format string) (string, error) {
+	switch format {
+	case "yaml":
+		data, err := yaml.Marshal(frontmatter)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimRight(string(data), "\n"), nil
+	case "toml":
+		var buf bytes.Buffer
+		if err := toml.NewEncoder(&buf).Encode(frontmatter); err != nil {
+			return "", err
+		}
+		return strings.TrimRight(buf.String(), "\n"), nil
+	case "json":
+		data, err := json.MarshalIndent(frontmatter, "", "  ")
+		if err != nil {
+			return "", err
+		}
+		return string(data), nil
+	}
+	return "", fmt.Errorf("unknown frontmatter format %q (use yaml, toml, or json)", format)
+}
+
+// convertDocument returns a document's frontmatter re-serialized in the
+// given format. With withBody set, the result is the whole document: the
+// fenced frontmatter followed by the unchanged body.
+func convertDocument(doc *Document, format string, withBody bool) (string, error) {
+	frontmatter, err := serializeFrontmatter(doc.Frontmatter, format)
+	if err != nil {
+		return "", err
+	}
+	if !withBody {
+		return frontmatter, nil
+	}
+
+	var output strings.Builder
+	if fence := frontmatterFences[format]; fence != "" {
+		output.WriteString(fence + "\n" + frontmatter + "\n" + fence + "\n")
+	} else {
+		output.WriteString(frontmatter + "\n")
+	}
+	output.WriteString(doc.Body)
+	return output.String(), nil
+}
+
+// FormatConverted converts the frontmatter of each document, adding a
+// "==> file <==" header before each one when there are several
+func FormatConverted(docs []*Document, format string, withBody bool) (string, error) {
+	var output strings.Builder
+	for i, doc := range docs {
+		converted, err := convertDocument(doc, format, withBody)
+		if err != nil {
+			return "", fmt.Errorf("%s: %v", doc.FilePath, err)
+		}
+
+		if len(docs) > 1 {
+			if i > 0 {
+				output.WriteString("\n")
+			}
+			output.WriteString(fmt.Sprintf("==> %s <==\n", doc.FilePath))
+		}
+		output.WriteString(converted)
+		output.WriteString("\n")
+	}
+	return strings.TrimRight(output.String(), "\n"), nil
+}
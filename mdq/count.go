@@ -0,0 +1,152 @@
+package mdq
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// QueryCount is the number of matches of one query in one file
+type QueryCount struct {
+	File  string `json:"file"`
+	Query string `json:"query"`
+	Count int    `json:"count"`
+}
+
+// CountQueries counts the matches of each query in each document. A
+// section query counts the sections it selects, so an explicit index counts
+// 0 or 1; a frontmatter query counts 1 when the field exists, even empty.
+func CountQueries(docs []*Document, queries []*Query, opts Options) []*QueryCount {
+	var counts []*QueryCount
+	for _, doc := range docs {
+		for _, query := range queries {
+			count := 0
+			for _, result := range ExecuteQuery(doc, query, opts) {
+				if result.Matched {
+					count++
+				}
+			}
+			counts = append(counts, &QueryCount{File: doc.FilePath, Query: formatQuery(query), Count: count})
+		}
+	}
+	return counts
+}
+
+// FormatCounts formats query counts. Text output prefixes each count with
+// its file and query only when there are several of them.
+func FormatCounts(counts []*QueryCount, opts Options) string {
+	var files, queries []string
+	seenFiles := make(map[string]bool)
+	seenQueries := make(map[string]bool)
+	for _, count := range counts {
+		if !seenFiles[count.File] {
+			seenFiles[count.File] = true
+			files = append(files, count.File)
+		}
+		if !seenQueries[count.Query] {
+			seenQueries[count.Query] = true
+			queries = append(queries, count.Query)
+		}
+	}
+
+	if opts.CSVOutput || opts.TSVOutput {
+		return formatCountsCSV(counts, files, queries, opts)
+	}
+	if opts.JSONOutput {
+		return formatCountsJSON(counts, files, opts)
+	}
+
+	var output strings.Builder
+	for _, count := range counts {
+		if !opts.RawOutput {
+			if len(files) > 1 {
+				output.WriteString(count.File + ": ")
+			}
+			if len(queries) > 1 {
+				output.WriteString(count.Query + ": ")
+			}
+		}
+		output.WriteString(fmt.Sprintf("%d\n", count.Count))
+	}
+	return strings.TrimRight(output.String(), "\n")
+}
+
+// formatCountsJSON formats counts as JSON, either as {file, query, count}
+// objects or, in object mode, one object per file keyed by query
+func formatCountsJSON(counts []*QueryCount, files []string, opts Options) string {
+	// JSON Lines: one compact count per line
+	if opts.JSONLines {
+		var output strings.Builder
+		for _, count := range counts {
+			data, err := json.Marshal(count)
+			if err != nil {
+				continue
+			}
+			output.Write(data)
+			output.WriteString("\n")
+		}
+		return strings.TrimRight(output.String(), "\n")
+	}
+
+	var value interface{} = counts
+	if opts.ObjectOutput {
+		objects := make(map[string]*orderedObject)
+		for _, count := range counts {
+			if _, ok := objects[count.File]; !ok {
+				objects[count.File] = newOrderedObject()
+				objects[count.File].Set("file", count.File)
+			}
+			objects[count.File].Set(count.Query, count.Count)
+		}
+		ordered := make([]*orderedObject, len(files))
+		for i, file := range files {
+			ordered[i] = objects[file]
+		}
+		value = ordered
+		if len(ordered) == 1 {
+			value = ordered[0]
+		}
+	} else if len(counts) == 1 {
+		value = counts[0]
+	}
+
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+// formatCountsCSV formats counts as CSV (or TSV) with a row per file and a
+// column per query
+func formatCountsCSV(counts []*QueryCount, files, queries []string, opts Options) string {
+	if len(counts) == 0 {
+		return ""
+	}
+
+	values := make(map[string]map[string]int)
+	for _, count := range counts {
+		if values[count.File] == nil {
+			values[count.File] = make(map[string]int)
+		}
+		values[count.File][count.Query] = count.Count
+	}
+
+	var output strings.Builder
+	writer := csv.NewWriter(&output)
+	if opts.TSVOutput {
+		writer.Comma = '\t'
+	}
+	writer.Write(append([]string{"file"}, queries...))
+	for _, file := range files {
+		row := []string{file}
+		for _, query := range queries {
+			row = append(row, strconv.Itoa(values[file][query]))
+		}
+		writer.Write(row)
+	}
+	writer.Flush()
+	return strings.TrimRight(output.String(), "\n")
+}
@@ -0,0 +1,28 @@
+package mdq
+
+import "testing"
+
+func TestCountQueries(t *testing.T) {
+	doc, _ := ParseDocument("---\ntitle: T\nempty:\n---\n## A\n\n## A\n", "a.md", false)
+
+	tests := []struct {
+		query string
+		opts  Options
+		want  int
+	}{
+		{"title", Options{}, 1},
+		{"title", Options{HeadOnly: true}, 1},
+		{"empty", Options{}, 1},
+		{"missing", Options{}, 0},
+		{"##A", Options{}, 2},
+		{"##A", Options{BodyOnly: true}, 2},
+		{"##A[1]", Options{}, 1},
+		{"##A[5]", Options{}, 0},
+	}
+	for _, tt := range tests {
+		counts := CountQueries([]*Document{doc}, []*Query{mustParseQuery(t, tt.query)}, tt.opts)
+		if got := counts[0].Count; got != tt.want {
+			t.Errorf("count of %q with %+v = %d, want %d", tt.query, tt.opts, got, tt.want)
+		}
+	}
+}
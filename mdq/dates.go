@@ -0,0 +1,103 @@
+package mdq
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// dateLayouts are the string date formats recognized in frontmatter and on
+// the command line. The bool marks layouts with no time of day.
+var dateLayouts = []struct {
+	layout   string
+	dateOnly bool
+}{
+	{time.RFC3339Nano, false},
+	{"2006-01-02T15:04:05", false},
+	{"2006-01-02 15:04:05 -0700 MST", false},
+	{"2006-01-02 15:04:05", false},
+	{"2006-01-02 15:04", false},
+	{"2006-01-02", true},
+	{"2006/01/02", true},
+	{"January 2, 2006", true},
+	{"Jan 2, 2006", true},
+	{"2 January 2006", true},
+	{"2 Jan 2006", true},
+}
+
+// parseDateString parses a date in any of the recognized layouts. dateOnly
+// reports whether the string had no time of day.
+func parseDateString(s string) (t time.Time, dateOnly bool, err error) {
+	s = strings.TrimSpace(s)
+	for _, dl := range dateLayouts {
+		if t, err := time.Parse(dl.layout, s); err == nil {
+			return t, dl.dateOnly, nil
+		}
+	}
+	return time.Time{}, false, fmt.Errorf("unrecognized date %q", s)
+}
+
+// frontmatterDate converts a frontmatter value (a YAML date or a date
+// string) to a time
+func frontmatterDate(value interface{}) (time.Time, bool) {
+	switch v := value.(type) {
+	case time.Time:
+		return v, true
+	case string:
+		t, _, err := parseDateString(v)
+		return t, err == nil
+	}
+	return time.Time{}, false
+}
+
+// DateRange is an inclusive range of dates for a frontmatter date field
+type DateRange struct {
+	Field string
+	Since time.Time // Zero for no lower bound
+	Until time.Time // Zero for no upper bound; exclusive once resolved
+}
+
+// ParseDateRange builds a date range from --since/--until values. A
+// date-only --until includes that whole day.
+func ParseDateRange(field, since, until string) (*DateRange, error) {
+	dateRange := &DateRange{Field: field}
+
+	if since != "" {
+		t, _, err := parseDateString(since)
+		if err != nil {
+			return nil, fmt.Errorf("--since: %v", err)
+		}
+		dateRange.Since = t
+	}
+
+	if until != "" {
+		t, dateOnly, err := parseDateString(until)
+		if err != nil {
+			return nil, fmt.Errorf("--until: %v", err)
+		}
+		if dateOnly {
+			t = t.AddDate(0, 0, 1)
+		} else {
+			t = t.Add(time.Nanosecond)
+		}
+		dateRange.Until = t
+	}
+
+	return dateRange, nil
+}
+
+// Contains reports whether a document's date field falls in the range.
+// Documents without a recognizable date are outside every range.
+func (r *DateRange) Contains(doc *Document) bool {
+	t, ok := frontmatterDate(doc.Frontmatter[r.Field])
+	if !ok {
+		return false
+	}
+	if !r.Since.IsZero() && t.Before(r.Since) {
+		return false
+	}
+	if !r.Until.IsZero() && !t.Before(r.Until) {
+		return false
+	}
+	return true
+}
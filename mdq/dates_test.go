@@ -0,0 +1,71 @@
+package mdq
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDateString(t *testing.T) {
+	tests := []struct {
+		input    string
+		want     time.Time
+		dateOnly bool
+	}{
+		{"2024-03-05", time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC), true},
+		{" 2024-03-05 ", time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC), true},
+		{"2024/03/05", time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC), true},
+		{"March 5, 2024", time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC), true},
+		{"Mar 5, 2024", time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC), true},
+		{"5 March 2024", time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC), true},
+		{"5 Mar 2024", time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC), true},
+		{"2024-03-05 14:30", time.Date(2024, 3, 5, 14, 30, 0, 0, time.UTC), false},
+		{"2024-03-05 14:30:15", time.Date(2024, 3, 5, 14, 30, 15, 0, time.UTC), false},
+		{"2024-03-05T14:30:15", time.Date(2024, 3, 5, 14, 30, 15, 0, time.UTC), false},
+		{"2024-03-05T14:30:15Z", time.Date(2024, 3, 5, 14, 30, 15, 0, time.UTC), false},
+		{"2024-03-05T14:30:15+02:00", time.Date(2024, 3, 5, 12, 30, 15, 0, time.UTC), false},
+	}
+	for _, tt := range tests {
+		got, dateOnly, err := parseDateString(tt.input)
+		if err != nil {
+			t.Errorf("%q: %v", tt.input, err)
+			continue
+		}
+		if !got.Equal(tt.want) || dateOnly != tt.dateOnly {
+			t.Errorf("%q = %v (date only %v), want %v (date only %v)", tt.input, got, dateOnly, tt.want, tt.dateOnly)
+		}
+	}
+
+	for _, bad := range []string{"", "yesterday", "2024-13-01", "05/03/2024"} {
+		if _, _, err := parseDateString(bad); err == nil {
+			t.Errorf("%q parsed, want an error", bad)
+		}
+	}
+}
+
+func TestDateRangeContains(t *testing.T) {
+	dateRange, err := ParseDateRange("date", "2024-01-01", "2024-12-31")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		frontmatter string
+		want        bool
+	}{
+		{"date: 2024-01-01", true},           // YAML date on the lower bound
+		{"date: 2024-12-31T23:59:59Z", true}, // Late on the last day
+		{"date: 2025-01-01", false},
+		{"date: 2023-12-31", false},
+		{`date: "June 15, 2024"`, true}, // A string date
+		{`date: "15 Jun 2023"`, false},
+		{`date: "2024/06/15"`, true},
+		{"date: someday", false},
+		{"title: no date", false},
+	}
+	for _, tt := range tests {
+		doc, _ := ParseDocument("---\n"+tt.frontmatter+"\n---\n", "a.md", false)
+		if got := dateRange.Contains(doc); got != tt.want {
+			t.Errorf("%s: in 2024 = %v, want %v", tt.frontmatter, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package mdq
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// DuplicateOccurrence is one heading within a group of duplicates
+type DuplicateOccurrence struct {
+	Line    int    `json:"line"`
+	Heading string `json:"heading"`
+}
+
+// DuplicateGroup is a set of headings in one file that share a title
+type DuplicateGroup struct {
+	File        string                `json:"file"`
+	Title       string                `json:"title"`
+	Anchor      string                `json:"anchor"`
+	Count       int                   `json:"count"`
+	Occurrences []DuplicateOccurrence `json:"occurrences"`
+}
+
+// findDuplicates groups a document's sections by normalized title (their
+// anchor slug) and returns the groups with more than one section, in order
+// of first occurrence
+func findDuplicates(doc *Document) []*DuplicateGroup {
+	groups := make(map[string]*DuplicateGroup)
+	var order []string
+
+	for _, section := range doc.Sections {
+		anchor := slugify(section.Title)
+		group, ok := groups[anchor]
+		if !ok {
+			group = &DuplicateGroup{
+				File:   doc.FilePath,
+				Title:  section.Title,
+				Anchor: anchor,
+			}
+			groups[anchor] = group
+			order = append(order, anchor)
+		}
+		group.Count++
+		group.Occurrences = append(group.Occurrences, DuplicateOccurrence{
+			Line:    section.StartLine,
+			Heading: section.Heading,
+		})
+	}
+
+	var duplicates []*DuplicateGroup
+	for _, anchor := range order {
+		if groups[anchor].Count > 1 {
+			duplicates = append(duplicates, groups[anchor])
+		}
+	}
+	return duplicates
+}
+
+// FormatDuplicates reports duplicate headings across documents as text, or as
+// a JSON array of groups when JSON output is requested
+func FormatDuplicates(docs []*Document, opts Options) string {
+	groups := []*DuplicateGroup{}
+	for _, doc := range docs {
+		groups = append(groups, findDuplicates(doc)...)
+	}
+
+	if opts.JSONOutput {
+		data, err := json.MarshalIndent(groups, "", "  ")
+		if err != nil {
+			return ""
+		}
+		return string(data)
+	}
+
+	var output strings.Builder
+	for gi, group := range groups {
+		if gi > 0 {
+			output.WriteString("\n")
+		}
+		output.WriteString(fmt.Sprintf("%s: %q (%d occurrences)\n", group.File, group.Title, group.Count))
+		for _, occurrence := range group.Occurrences {
+			output.WriteString(fmt.Sprintf("  %s:%d: %s\n", group.File, occurrence.Line, occurrence.Heading))
+		}
+	}
+	return strings.TrimRight(output.String(), "\n")
+}
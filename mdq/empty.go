@@ -0,0 +1,58 @@
+package mdq
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// EmptySection is a heading with no content beneath it
+type EmptySection struct {
+	File    string `json:"file"`
+	Line    int    `json:"line"`
+	Heading string `json:"heading"`
+}
+
+// findEmptySections returns the sections of a document whose body is blank.
+// A section directly followed by a subsection isn't empty, since the
+// subsection is its content.
+func findEmptySections(doc *Document) []*EmptySection {
+	var empty []*EmptySection
+	for i, section := range doc.Sections {
+		if strings.TrimSpace(section.Body) != "" {
+			continue
+		}
+		if i+1 < len(doc.Sections) && doc.Sections[i+1].Level > section.Level {
+			continue
+		}
+		empty = append(empty, &EmptySection{
+			File:    doc.FilePath,
+			Line:    section.StartLine,
+			Heading: section.Heading,
+		})
+	}
+	return empty
+}
+
+// FormatEmptySections reports empty sections across documents as text, or as
+// a JSON array when JSON output is requested
+func FormatEmptySections(docs []*Document, opts Options) string {
+	sections := []*EmptySection{}
+	for _, doc := range docs {
+		sections = append(sections, findEmptySections(doc)...)
+	}
+
+	if opts.JSONOutput {
+		data, err := json.MarshalIndent(sections, "", "  ")
+		if err != nil {
+			return ""
+		}
+		return string(data)
+	}
+
+	var output strings.Builder
+	for _, section := range sections {
+		output.WriteString(fmt.Sprintf("%s:%d: %s\n", section.File, section.Line, section.Heading))
+	}
+	return strings.TrimRight(output.String(), "\n")
+}
@@ -0,0 +1,118 @@
+package mdq
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Footnote is a footnote definition: "[^label]: text"
+type Footnote struct {
+	Label string
+	Text  string
+}
+
+var (
+	footnoteDefPattern = regexp.MustCompile(`^\[\^([^\]\s]+)\]:\s?(.*)$`)
+	footnoteRefPattern = regexp.MustCompile(`\[\^([^\]\s]+)\]`)
+)
+
+// parseFootnotes returns the footnote definitions in a body, in order.
+// Indented lines following a definition are joined onto its text.
+// Definitions in fenced code blocks are skipped.
+func parseFootnotes(body string) []Footnote {
+	var footnotes []Footnote
+	var current *Footnote
+	var fence codeFence
+
+	for _, line := range strings.Split(body, "\n") {
+		if fence.update(line) || fence.open() {
+			current = nil
+			continue
+		}
+		if matches := footnoteDefPattern.FindStringSubmatch(line); matches != nil {
+			footnotes = append(footnotes, Footnote{Label: matches[1], Text: strings.TrimSpace(matches[2])})
+			current = &footnotes[len(footnotes)-1]
+			continue
+		}
+
+		// Continuation lines are indented; anything else ends the definition
+		if current != nil && (strings.HasPrefix(line, "    ") || strings.HasPrefix(line, "\t")) {
+			current.Text = strings.TrimSpace(current.Text + " " + strings.TrimSpace(line))
+			continue
+		}
+		current = nil
+	}
+
+	return footnotes
+}
+
+// footnoteRefs returns the labels of footnotes referenced in a body, in
+// order of first reference. Definitions and references in fenced code
+// blocks don't count.
+func footnoteRefs(body string) []string {
+	var labels []string
+	var fence codeFence
+	seen := make(map[string]bool)
+
+	for _, line := range strings.Split(body, "\n") {
+		if fence.update(line) || fence.open() {
+			continue
+		}
+		for _, match := range footnoteRefPattern.FindAllStringSubmatchIndex(line, -1) {
+			// A reference followed by a colon is a definition
+			if match[1] < len(line) && line[match[1]] == ':' {
+				continue
+			}
+			label := line[match[2]:match[3]]
+			if !seen[label] {
+				labels = append(labels, label)
+				seen[label] = true
+			}
+		}
+	}
+
+	return labels
+}
+
+// footnoteResults returns a result per footnote referenced or defined in the
+// matched sections. Referenced footnotes are resolved against definitions
+// anywhere in the document, since they're usually collected at the end.
+func footnoteResults(doc *Document, query *Query, sections []Section, opts Options) []*QueryResult {
+	// Collect definitions from the whole document
+	definitions := make(map[string]string)
+	for _, section := range doc.Sections {
+		for _, footnote := range parseFootnotes(section.Body) {
+			if _, ok := definitions[footnote.Label]; !ok {
+				definitions[footnote.Label] = footnote.Text
+			}
+		}
+	}
+
+	var results []*QueryResult
+	for _, section := range sections {
+		labels := footnoteRefs(section.Body)
+		for _, footnote := range parseFootnotes(section.Body) {
+			labels = append(labels, footnote.Label)
+		}
+
+		seen := make(map[string]bool)
+		for _, label := range labels {
+			if seen[label] {
+				continue
+			}
+			seen[label] = true
+
+			result := newResult(doc, query)
+			result.Matched = true
+			if !opts.BodyOnly {
+				result.Heading = "[^" + label + "]"
+			}
+			if !opts.HeadOnly {
+				result.Body = definitions[label]
+			}
+			results = append(results, result)
+		}
+	}
+
+	return results
+}
@@ -0,0 +1,49 @@
+package mdq
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFootnotesSkipFences(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		defs []Footnote
+		refs []string
+	}{
+		{
+			name: "plain",
+			body: "Text[^a].\n\n[^a]: Note A\n    continued\n",
+			defs: []Footnote{{Label: "a", Text: "Note A continued"}},
+			refs: []string{"a"},
+		},
+		{
+			name: "definition in fence",
+			body: "Text[^a].\n\n```markdown\n[^b]: Not a note\n```\n[^a]: Note A\n",
+			defs: []Footnote{{Label: "a", Text: "Note A"}},
+			refs: []string{"a"},
+		},
+		{
+			name: "reference in fence",
+			body: "~~~\nsee[^b]\n~~~\nText[^a].\n",
+			refs: []string{"a"},
+		},
+		{
+			name: "fence ends a definition",
+			body: "[^a]: Note A\n```\n    code[^c]\n```\n",
+			defs: []Footnote{{Label: "a", Text: "Note A"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseFootnotes(tt.body); !reflect.DeepEqual(got, tt.defs) {
+				t.Errorf("parseFootnotes() = %+v, want %+v", got, tt.defs)
+			}
+			if got := footnoteRefs(tt.body); !reflect.DeepEqual(got, tt.refs) {
+				t.Errorf("footnoteRefs() = %q, want %q", got, tt.refs)
+			}
+		})
+	}
+}
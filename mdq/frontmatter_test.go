@@ -0,0 +1,168 @@
+package mdq
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+// frontmatterField runs a frontmatter query, failing the test if it doesn't match
+func frontmatterField(t *testing.T, doc *Document, field string) string {
+	t.Helper()
+	results := ExecuteQuery(doc, mustParseQuery(t, field), Options{})
+	if len(results) != 1 || !results[0].Matched {
+		t.Errorf("%s: no match", field)
+		return ""
+	}
+	return results[0].Body
+}
+
+func TestYAMLMergeKeysWithDottedPaths(t *testing.T) {
+	content, err := os.ReadFile("../test4.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err := ParseDocument(string(content), "test4.md", false)
+	if err != nil || doc.FrontmatterError != nil {
+		t.Fatalf("parse: %v, frontmatter: %v", err, doc.FrontmatterError)
+	}
+
+	tests := []struct {
+		field string
+		want  string
+	}{
+		{"settings.layout", "post"},    // From the merged anchor
+		{"settings.comments", "false"}, // Overridden after the merge
+		{"reviewer.name", "Test User"}, // Through a plain alias
+		{"reviewer.email", "test@example.com"},
+		{"defaults.comments", "true"}, // The anchor itself is unchanged
+	}
+	for _, tt := range tests {
+		if got := frontmatterField(t, doc, tt.field); got != tt.want {
+			t.Errorf("%s = %q, want %q", tt.field, got, tt.want)
+		}
+	}
+}
+
+func TestYAMLNestedMergeKeys(t *testing.T) {
+	content := `---
+base: &base
+  site:
+    lang: en
+    theme: dark
+extra: &extra
+  tags: [a, b]
+page:
+  <<: [*base, *extra]
+  site:
+    lang: fr
+---
+`
+	doc, _ := ParseDocument(content, "a.md", false)
+	tests := []struct {
+		field string
+		want  string
+	}{
+		{"page.site.lang", "fr"},
+		{"page.tags[1]", "b"},
+		{"base.site.theme", "dark"},
+	}
+	for _, tt := range tests {
+		if got := frontmatterField(t, doc, tt.field); got != tt.want {
+			t.Errorf("%s = %q, want %q", tt.field, got, tt.want)
+		}
+	}
+}
+
+func TestLimitFrontmatterDepth(t *testing.T) {
+	// Thousands of levels of flow-style lists and maps
+	deep := "---\nbomb: " + strings.Repeat("[{a: ", 2500) + "x" + strings.Repeat("}]", 2500) + "\ntitle: T\n---\n# Body\n"
+	doc, err := ParseDocument(deep, "deep.md", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc.FrontmatterError != nil {
+		t.Fatalf("frontmatter error before the limit: %v", doc.FrontmatterError)
+	}
+
+	LimitFrontmatterDepth(doc, 64)
+	if doc.FrontmatterError == nil {
+		t.Error("no error for frontmatter nested 5000 levels deep")
+	}
+	if len(doc.Frontmatter) != 0 {
+		t.Errorf("deep frontmatter kept: %d fields", len(doc.Frontmatter))
+	}
+	if len(doc.Sections) != 1 {
+		t.Errorf("got %d sections, want the body parsed anyway", len(doc.Sections))
+	}
+
+	// Nesting up to the limit is kept and queryable; each step of a path
+	// like a.b.c[1] is a level
+	shallow := "---\na:\n  b:\n    c: [x, y]\n---\n"
+	doc, _ = ParseDocument(shallow, "a.md", false)
+	LimitFrontmatterDepth(doc, 4)
+	if doc.FrontmatterError != nil {
+		t.Fatalf("error at the limit: %v", doc.FrontmatterError)
+	}
+	if got := frontmatterField(t, doc, "a.b.c[1]"); got != "y" {
+		t.Errorf("a.b.c[1] = %q, want %q", got, "y")
+	}
+	LimitFrontmatterDepth(doc, 3)
+	if doc.FrontmatterError == nil {
+		t.Error("no error one level past the limit")
+	}
+}
+
+func TestTOMLFrontmatter(t *testing.T) {
+	content := `+++
+title = "Hugo Post"
+weight = 42
+tags = ["go", "hugo"]
+draft = false
+
+[params]
+author = "A"
++++
+# Heading
+
+Body.
+`
+	doc, err := ParseDocument(content, "post.md", false)
+	if err != nil || doc.FrontmatterError != nil {
+		t.Fatalf("parse: %v, frontmatter: %v", err, doc.FrontmatterError)
+	}
+	if doc.FrontmatterFormat != "toml" {
+		t.Errorf("format = %q, want toml", doc.FrontmatterFormat)
+	}
+
+	if got, ok := doc.Frontmatter["title"].(string); !ok || got != "Hugo Post" {
+		t.Errorf("title = %#v, want the string %q", doc.Frontmatter["title"], "Hugo Post")
+	}
+	if got, ok := doc.Frontmatter["weight"].(int64); !ok || got != 42 {
+		t.Errorf("weight = %#v, want the int 42", doc.Frontmatter["weight"])
+	}
+	if got, ok := doc.Frontmatter["tags"].([]interface{}); !ok || len(got) != 2 || got[0] != "go" || got[1] != "hugo" {
+		t.Errorf("tags = %#v, want [go hugo]", doc.Frontmatter["tags"])
+	}
+
+	tests := []struct {
+		field string
+		want  string
+	}{
+		{"title", "Hugo Post"},
+		{"weight", "42"},
+		{"tags", "go, hugo"},
+		{"tags[1]", "hugo"},
+		{"draft", "false"},
+		{"params.author", "A"},
+	}
+	for _, tt := range tests {
+		if got := frontmatterField(t, doc, tt.field); got != tt.want {
+			t.Errorf("%s = %q, want %q", tt.field, got, tt.want)
+		}
+	}
+
+	if len(doc.Sections) != 1 || doc.Sections[0].Body != "\nBody." {
+		t.Errorf("sections = %#v, want one with the body after the +++ fence", doc.Sections)
+	}
+}
@@ -0,0 +1,25 @@
+package mdq
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+// normalizeForHash normalizes a section body so that changes which don't
+// affect content (line endings, trailing whitespace, surrounding blank
+// lines) don't change its hash
+func normalizeForHash(body string) string {
+	body = strings.ReplaceAll(body, "\r\n", "\n")
+	lines := strings.Split(body, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t\r")
+	}
+	return strings.Trim(strings.Join(lines, "\n"), "\n")
+}
+
+// sectionHash returns the hex SHA-256 of a normalized section body
+func sectionHash(body string) string {
+	sum := sha256.Sum256([]byte(normalizeForHash(body)))
+	return hex.EncodeToString(sum[:])
+}
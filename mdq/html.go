@@ -0,0 +1,93 @@
+package mdq
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"strings"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+)
+
+// htmlRenderer renders markdown with the GitHub extensions (tables,
+// strikethrough, task lists, autolinks). Raw HTML in the markdown is
+// omitted, so output is safe to embed.
+var htmlRenderer = goldmark.New(goldmark.WithExtensions(extension.GFM))
+
+// formatHTML renders each matched section to an HTML fragment. Queried
+// frontmatter fields come first as a definition list. With several files,
+// each file's fragments are wrapped in a <section> naming the file.
+func formatHTML(results []*QueryResult, opts Options) string {
+	var output strings.Builder
+
+	groups := groupByFile(results)
+	for gi, group := range groups {
+		if len(groups) > 1 {
+			if gi > 0 {
+				output.WriteString("\n")
+			}
+			output.WriteString(fmt.Sprintf("<section data-file=\"%s\">\n", html.EscapeString(group.File)))
+		}
+
+		writeHTMLFields(&output, group.Results)
+
+		for _, result := range group.Results {
+			if result.Type != "section" {
+				continue
+			}
+
+			// Render the same heading and body markdown output would show
+			var markdown []string
+			if result.Heading != "" && !opts.BodyOnly {
+				markdown = append(markdown, result.Heading)
+			}
+			if result.Body != "" && !opts.HeadOnly {
+				markdown = append(markdown, result.Body)
+			}
+			if len(markdown) == 0 {
+				continue
+			}
+			output.WriteString(renderHTML(strings.Join(markdown, "\n\n")))
+		}
+
+		if len(groups) > 1 {
+			output.WriteString("</section>\n")
+		}
+	}
+
+	return strings.TrimRight(output.String(), "\n")
+}
+
+// writeHTMLFields writes the queried frontmatter fields that have values
+// as a <dl> of field names and values
+func writeHTMLFields(output *strings.Builder, results []*QueryResult) {
+	var fields strings.Builder
+	for _, result := range results {
+		if result.Type == "section" || result.Body == "" {
+			continue
+		}
+
+		fieldName := result.Heading
+		if fieldName == "" {
+			fieldName = result.Query
+		}
+		fields.WriteString(fmt.Sprintf("<dt>%s</dt>\n<dd>%s</dd>\n", html.EscapeString(fieldName), html.EscapeString(result.Body)))
+	}
+
+	if fields.Len() > 0 {
+		output.WriteString("<dl>\n")
+		output.WriteString(fields.String())
+		output.WriteString("</dl>\n")
+	}
+}
+
+// renderHTML converts markdown to HTML, falling back to the escaped
+// markdown in a <pre> if it can't be rendered
+func renderHTML(markdown string) string {
+	var buf bytes.Buffer
+	if err := htmlRenderer.Convert([]byte(markdown), &buf); err != nil {
+		return "<pre>" + html.EscapeString(markdown) + "</pre>\n"
+	}
+	return buf.String()
+}
@@ -0,0 +1,114 @@
+package mdq
+
+import (
+	"encoding/csv"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var (
+	// htmlImagePattern matches an HTML <img> tag
+	htmlImagePattern = regexp.MustCompile(`(?i)<img\b[^>]*>`)
+
+	// htmlAttributePattern matches a src or alt attribute with a double-quoted
+	// (2), single-quoted (3), or unquoted (4) value
+	htmlAttributePattern = regexp.MustCompile(`(?i)\b(src|alt)\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s"'>]+))`)
+)
+
+// htmlImages returns the <img> tags in a body as image links of kind
+// "html", skipping tags in code blocks and code spans and tags with no src
+func htmlImages(body string) []Link {
+	var images []Link
+	var fence codeFence
+	for i, line := range strings.Split(body, "\n") {
+		if fence.update(line) || fence.open() {
+			continue
+		}
+		line = plainInlineCode.ReplaceAllStringFunc(line, func(span string) string {
+			return strings.Repeat(" ", len(span))
+		})
+
+		for _, m := range htmlImagePattern.FindAllStringIndex(line, -1) {
+			image := Link{Kind: "html", Image: true, Line: i, Column: m[0]}
+			for _, attr := range htmlAttributePattern.FindAllStringSubmatch(line[m[0]:m[1]], -1) {
+				value := attr[2] + attr[3] + attr[4]
+				if strings.EqualFold(attr[1], "src") {
+					image.URL = value
+				} else {
+					image.Text = value
+				}
+			}
+			if image.URL != "" {
+				images = append(images, image)
+			}
+		}
+	}
+	return images
+}
+
+// parseImages returns the images in a body in order: markdown images, inline
+// or resolved against definitions, and HTML <img> tags. Images inside link
+// text, as in a badge, are included.
+func parseImages(body string, definitions map[string]string) []Link {
+	var images []Link
+	for _, link := range parseLinks(body, definitions) {
+		if link.Image {
+			images = append(images, link)
+		}
+	}
+	images = append(images, htmlImages(body)...)
+
+	sort.SliceStable(images, func(i, j int) bool {
+		if images[i].Line != images[j].Line {
+			return images[i].Line < images[j].Line
+		}
+		return images[i].Column < images[j].Column
+	})
+	return images
+}
+
+// imageResults returns a result per image in the matched sections, with the
+// alt text as the heading and the source as the body
+func imageResults(doc *Document, query *Query, sections []Section, opts Options) []*QueryResult {
+	definitions := linkDefinitions(doc.Body)
+
+	var results []*QueryResult
+	for _, section := range sections {
+		for _, image := range parseImages(section.Body, definitions) {
+			result := newResult(doc, query)
+			result.Matched = true
+			if !opts.BodyOnly {
+				result.Heading = image.Text
+			}
+			if !opts.HeadOnly {
+				result.Body = image.URL
+			}
+			result.Kind = image.Kind
+			result.Image = true
+			result.Start = section.sourceLine(image.Line)
+			result.End = result.Start
+			results = append(results, result)
+		}
+	}
+	return results
+}
+
+// formatImagesCSV formats image results as CSV (or TSV), a row per image
+func formatImagesCSV(results []*QueryResult, opts Options) string {
+	var output strings.Builder
+	writer := csv.NewWriter(&output)
+	if opts.TSVOutput {
+		writer.Comma = '\t'
+	}
+	writer.Write([]string{"file", "line", "alt", "src", "kind"})
+	for _, result := range results {
+		if !result.Matched {
+			continue
+		}
+		writer.Write([]string{result.File, strconv.Itoa(result.Start), result.Heading, result.Body, result.Kind})
+	}
+	writer.Flush()
+	return strings.TrimRight(output.String(), "\n")
+}
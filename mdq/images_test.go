@@ -0,0 +1,38 @@
+package mdq
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseImages(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want []Link
+	}{
+		{
+			name: "inline and html",
+			body: `![logo](logo.png) <img src="a.png" alt="A">`,
+			want: []Link{
+				{Text: "logo", URL: "logo.png", Kind: "inline", Image: true},
+				{Text: "A", URL: "a.png", Kind: "html", Image: true, Column: 18},
+			},
+		},
+		{
+			name: "image inside a link",
+			body: "Status: [![Build](https://ci/badge.svg)](https://ci/job)",
+			want: []Link{
+				{Text: "Build", URL: "https://ci/badge.svg", Kind: "inline", Image: true, Column: 9},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseImages(tt.body, nil); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
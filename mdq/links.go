@@ -0,0 +1,175 @@
+package mdq
+
+import (
+	"encoding/csv"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Link is a link found in a section body
+type Link struct {
+	Text   string // Link text, or the URL itself for an autolink
+	URL    string // Destination, resolved for reference links
+	Kind   string // "inline", "reference", "autolink", or "html" (an <img> tag)
+	Image  bool   // The link is an image (![alt](src))
+	Line   int    // 0-based line of the link within the body
+	Column int    // 0-based byte offset of the link within its line
+}
+
+var (
+	// linkPattern matches an autolink (group 1), or an optionally
+	// image-marked (2) bracketed text (3) followed by an inline destination
+	// (4), a reference label (5), or nothing (a shortcut reference). The
+	// text may contain balanced brackets one level deep, such as the image
+	// in a badge: [![alt](src)](url).
+	linkPattern = regexp.MustCompile(`<((?:https?|ftp|mailto):[^>\s]+)>|(!?)\[((?:[^\[\]]|\[[^\[\]]*\])*)\](?:\(\s*<?([^)\s>]*)>?(?:\s+[^)]*)?\)|\[([^\]]*)\])?`)
+
+	// linkDefinitionPattern matches a link reference definition: [label]: url
+	linkDefinitionPattern = regexp.MustCompile(`^ {0,3}\[([^\]^][^\]]*)\]:\s*<?([^\s>]+)>?`)
+)
+
+// normalizeLabel folds a reference label for matching, as labels are
+// case-insensitive and whitespace runs are equivalent
+func normalizeLabel(label string) string {
+	return strings.ToLower(strings.Join(strings.Fields(label), " "))
+}
+
+// linkDefinitions returns the link reference definitions in a body, keyed by
+// normalized label. The first definition of a label wins.
+func linkDefinitions(body string) map[string]string {
+	definitions := make(map[string]string)
+	var fence codeFence
+	for _, line := range strings.Split(body, "\n") {
+		if fence.update(line) || fence.open() {
+			continue
+		}
+		if matches := linkDefinitionPattern.FindStringSubmatch(line); matches != nil {
+			label := normalizeLabel(matches[1])
+			if _, ok := definitions[label]; !ok {
+				definitions[label] = matches[2]
+			}
+		}
+	}
+	return definitions
+}
+
+// parseLinks returns the links in a body, in order: inline links, reference
+// links resolved against definitions, and autolinks, with images marked.
+// Links in code blocks, code spans, and definitions themselves are skipped,
+// as are references without a definition.
+func parseLinks(body string, definitions map[string]string) []Link {
+	var links []Link
+	var fence codeFence
+	for i, line := range strings.Split(body, "\n") {
+		if fence.update(line) || fence.open() || linkDefinitionPattern.MatchString(line) {
+			continue
+		}
+
+		// Blank out code spans so their contents aren't read as links
+		line = plainInlineCode.ReplaceAllStringFunc(line, func(span string) string {
+			return strings.Repeat(" ", len(span))
+		})
+
+		links = append(links, lineLinks(line, i, 0, definitions)...)
+	}
+	return links
+}
+
+// lineLinks returns the links in text on body line i, where text starts at
+// the given column. Link text is scanned too, so the image in a badge is
+// found after its link, whose text is the image's alt text.
+func lineLinks(text string, i, column int, definitions map[string]string) []Link {
+	var links []Link
+	for _, m := range linkPattern.FindAllStringSubmatchIndex(text, -1) {
+		group := func(n int) string {
+			if m[2*n] < 0 {
+				return ""
+			}
+			return text[m[2*n]:m[2*n+1]]
+		}
+
+		if m[2] >= 0 {
+			links = append(links, Link{Text: group(1), URL: group(1), Kind: "autolink", Line: i, Column: column + m[0]})
+			continue
+		}
+
+		inner := lineLinks(group(3), i, column+m[6], definitions)
+		link := Link{Text: imageAltText(group(3)), Image: group(2) == "!", Line: i, Column: column + m[0]}
+		switch {
+		case m[8] >= 0:
+			link.URL, link.Kind = group(4), "inline"
+		default:
+			// A full [text][label], collapsed [text][], or shortcut [text]
+			// reference, which is only a link if the label is defined
+			label := group(5)
+			if label == "" {
+				label = group(3)
+			}
+			url, ok := definitions[normalizeLabel(label)]
+			if !ok || strings.HasPrefix(label, "^") {
+				links = append(links, inner...)
+				continue
+			}
+			link.URL, link.Kind = url, "reference"
+		}
+		links = append(links, link)
+		links = append(links, inner...)
+	}
+	return links
+}
+
+// imageAltText replaces the images in link text with their alt text
+func imageAltText(text string) string {
+	return linkPattern.ReplaceAllStringFunc(text, func(match string) string {
+		if m := linkPattern.FindStringSubmatch(match); m[2] == "!" {
+			return m[3]
+		}
+		return match
+	})
+}
+
+// linkResults returns a result per link in the matched sections, with the
+// link text as the heading and the URL as the body. References are resolved
+// against definitions anywhere in the document.
+func linkResults(doc *Document, query *Query, sections []Section, opts Options) []*QueryResult {
+	definitions := linkDefinitions(doc.Body)
+
+	var results []*QueryResult
+	for _, section := range sections {
+		for _, link := range parseLinks(section.Body, definitions) {
+			result := newResult(doc, query)
+			result.Matched = true
+			if !opts.BodyOnly {
+				result.Heading = link.Text
+			}
+			if !opts.HeadOnly {
+				result.Body = link.URL
+			}
+			result.Kind = link.Kind
+			result.Image = link.Image
+			result.Start = section.sourceLine(link.Line)
+			result.End = result.Start
+			results = append(results, result)
+		}
+	}
+	return results
+}
+
+// formatLinksCSV formats link results as CSV (or TSV), a row per link
+func formatLinksCSV(results []*QueryResult, opts Options) string {
+	var output strings.Builder
+	writer := csv.NewWriter(&output)
+	if opts.TSVOutput {
+		writer.Comma = '\t'
+	}
+	writer.Write([]string{"file", "line", "text", "url", "kind", "image"})
+	for _, result := range results {
+		if !result.Matched {
+			continue
+		}
+		writer.Write([]string{result.File, strconv.Itoa(result.Start), result.Heading, result.Body, result.Kind, strconv.FormatBool(result.Image)})
+	}
+	writer.Flush()
+	return strings.TrimRight(output.String(), "\n")
+}
@@ -0,0 +1,52 @@
+package mdq
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseLinks(t *testing.T) {
+	definitions := map[string]string{"g": "https://guide", "i": "icon.png"}
+	tests := []struct {
+		name string
+		body string
+		want []Link
+	}{
+		{
+			name: "inline and autolink",
+			body: "See [docs](https://d) or <https://auto>.",
+			want: []Link{
+				{Text: "docs", URL: "https://d", Kind: "inline", Column: 4},
+				{Text: "https://auto", URL: "https://auto", Kind: "autolink", Column: 25},
+			},
+		},
+		{
+			name: "badge",
+			body: "[![Build](https://ci/badge.svg)](https://ci/job)",
+			want: []Link{
+				{Text: "Build", URL: "https://ci/job", Kind: "inline"},
+				{Text: "Build", URL: "https://ci/badge.svg", Kind: "inline", Image: true, Column: 1},
+			},
+		},
+		{
+			name: "image inside a reference link",
+			body: "x\n[the ![icon][i] guide][g]",
+			want: []Link{
+				{Text: "the icon guide", URL: "https://guide", Kind: "reference", Line: 1},
+				{Text: "icon", URL: "icon.png", Kind: "reference", Image: true, Line: 1, Column: 5},
+			},
+		},
+		{
+			name: "undefined reference",
+			body: "[nothing][none] and `[code](x)`",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseLinks(tt.body, definitions); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package mdq
+
+import (
+	"regexp"
+	"strings"
+)
+
+// ListItem is a top-level item of a list in a section body
+type ListItem struct {
+	Line int    // 0-based line of the item's marker within the body
+	Text string // The item with its marker removed and continuation lines dedented
+}
+
+// itemMarkerPattern matches a bullet (-, *, +) or ordered (1. or 1)) list
+// marker at any indentation, and the spaces after it
+var itemMarkerPattern = regexp.MustCompile(`^(\s*)([-*+]|\d{1,9}[.)])(\s+|$)`)
+
+// parseListItems returns the top-level list items in a body, in order.
+// Nested items and continuation lines belong to the item above them, and a
+// list ends at an unindented line after a blank one. Lines in fenced code
+// blocks are never markers.
+func parseListItems(body string) []ListItem {
+	var items []ListItem
+	var lines []string
+	start, contentIndent, top := -1, 0, -1
+	previousBlank, codeInItem := false, false
+	var fence codeFence
+
+	flush := func() {
+		if start >= 0 {
+			items = append(items, ListItem{Line: start, Text: strings.TrimRight(strings.Join(lines, "\n"), "\n")})
+		}
+		start, lines = -1, nil
+	}
+
+	for i, line := range strings.Split(body, "\n") {
+		// Lines inside a code block go with the item the block started in
+		inCode := fence.open()
+		opensCode := fence.update(line) && !inCode
+		if inCode {
+			if codeInItem {
+				lines = append(lines, dedent(line, contentIndent))
+			}
+			continue
+		}
+
+		blank := strings.TrimSpace(line) == ""
+		var marker []string
+		if !opensCode {
+			marker = itemMarkerPattern.FindStringSubmatch(line)
+		}
+		switch {
+		case marker != nil && (top < 0 || indentWidth(line) <= top):
+			// A new top-level item
+			flush()
+			top = indentWidth(line)
+			start = i
+			contentIndent = len(marker[0])
+			if marker[3] == "" {
+				contentIndent++
+			}
+			lines = []string{line[len(marker[0]):]}
+		case start < 0:
+			// Not in a list
+		case blank:
+			lines = append(lines, "")
+		case indentWidth(line) > top || !previousBlank:
+			// A nested item, an indented continuation, or a lazy one
+			lines = append(lines, dedent(line, contentIndent))
+		default:
+			// Unindented text after a blank line ends the list
+			flush()
+			top = -1
+		}
+		previousBlank = blank
+		codeInItem = opensCode && start >= 0
+	}
+	flush()
+
+	return items
+}
+
+// dedent removes up to n columns of leading spaces from a line, or one tab
+func dedent(line string, n int) string {
+	if strings.HasPrefix(line, "\t") {
+		return line[1:]
+	}
+	trimmed := strings.TrimLeft(line, " ")
+	if removed := len(line) - len(trimmed); removed > n {
+		return line[n:]
+	}
+	return trimmed
+}
+
+// listItemResults returns a result per top-level list item in the matched
+// sections, or only the item at the query's item index
+func listItemResults(doc *Document, query *Query, sections []Section, opts Options) []*QueryResult {
+	var results []*QueryResult
+	for _, section := range sections {
+		for _, item := range parseListItems(section.Body) {
+			result := newResult(doc, query)
+			result.Matched = true
+			result.Title = section.Title
+			result.Body = item.Text
+			result.Start = section.sourceLine(item.Line)
+			result.End = section.sourceLine(item.Line + strings.Count(item.Text, "\n"))
+			results = append(results, result)
+		}
+	}
+
+	if !query.ExplicitItemIndex {
+		return results
+	}
+	index := query.ItemIndex
+	if index < 0 {
+		index += len(results)
+	}
+	if index < 0 || index >= len(results) {
+		verbosef(opts, doc.FilePath, "%s: item %d out of range (%d items)", formatQuery(query), query.ItemIndex, len(results))
+		result := newResult(doc, query)
+		result.Missing = true
+		return []*QueryResult{result}
+	}
+	return results[index : index+1]
+}
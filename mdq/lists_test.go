@@ -0,0 +1,30 @@
+package mdq
+
+import "testing"
+
+func TestListItemLinesWithoutBlocks(t *testing.T) {
+	content := "# Doc\n\n## Tasks\n\n```sh\nmake\nmake test\n```\n\n- first\n- second\n  continued\n\n~~~\nmore code\n~~~\n- [a link](https://example.com)\n"
+
+	for _, noBlocks := range []bool{false, true} {
+		doc, err := ParseDocument(content, "tasks.md", noBlocks)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		items := ExecuteQuery(doc, mustParseQuery(t, "##Tasks/-"), Options{})
+		want := [][2]int{{10, 10}, {11, 12}, {17, 17}}
+		if len(items) != len(want) {
+			t.Fatalf("noBlocks=%v: got %d items, want %d", noBlocks, len(items), len(want))
+		}
+		for i, item := range items {
+			if got := [2]int{item.Start, item.End}; got != want[i] {
+				t.Errorf("noBlocks=%v: item %d (%q) spans %v, want %v", noBlocks, i, item.Body, got, want[i])
+			}
+		}
+
+		links := ExecuteQuery(doc, mustParseQuery(t, "##Tasks"), Options{Links: true})
+		if len(links) != 1 || links[0].Start != 17 {
+			t.Errorf("noBlocks=%v: got link results %+v, want one starting on line 17", noBlocks, links)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package mdq
+
+import (
+	"encoding/json"
+	"os"
+)
+
+// Manifest statuses for processed files
+const (
+	ManifestOK         = "ok"
+	ManifestParseError = "parse-error" // Parsing failed, or the frontmatter couldn't be read
+	ManifestSkipped    = "skipped"
+)
+
+// ManifestEntry records how a single input file was processed
+type ManifestEntry struct {
+	File              string `json:"file"`
+	Status            string `json:"status"`
+	Error             string `json:"error,omitempty"`
+	Matches           int    `json:"matches"`
+	FrontmatterFormat string `json:"frontmatterFormat,omitempty"`
+}
+
+// CountMatches fills in each entry's match count from the matched results
+// for its file, including matches whose content is empty
+func CountMatches(entries []*ManifestEntry, results []*QueryResult) {
+	counts := make(map[string]int)
+	for _, result := range results {
+		if result.Matched {
+			counts[result.File]++
+		}
+	}
+	for _, entry := range entries {
+		if entry.Status != ManifestSkipped {
+			entry.Matches = counts[entry.File]
+		}
+	}
+}
+
+// WriteManifest writes the manifest entries to path as a JSON array
+func WriteManifest(path string, entries []*ManifestEntry) error {
+	if entries == nil {
+		entries = []*ManifestEntry{}
+	}
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
@@ -0,0 +1,32 @@
+package mdq
+
+import "testing"
+
+func TestCountMatchesEmptyContent(t *testing.T) {
+	doc, _ := ParseDocument("---\nempty:\ntitle: T\n---\n# Empty\n# Full\ntext\n", "a.md", false)
+	tests := []struct {
+		name    string
+		queries string
+		opts    Options
+		want    int
+	}{
+		{"empty section body", "#[0]", Options{BodyOnly: true}, 1},
+		{"sections", "#", Options{}, 2},
+		{"hash", "#", Options{Hash: true}, 2},
+		{"lines", "#", Options{Lines: true, BodyOnly: true}, 2},
+		{"empty frontmatter value", "empty", Options{}, 1},
+		{"missing index", "#[5]", Options{}, 0},
+		{"missing field", "nope", Options{}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry := &ManifestEntry{File: "a.md", Status: ManifestOK}
+			results := ExecuteQueries([]*Document{doc}, []*Query{mustParseQuery(t, tt.queries)}, tt.opts)
+			CountMatches([]*ManifestEntry{entry}, results)
+			if entry.Matches != tt.want {
+				t.Errorf("got %d matches, want %d", entry.Matches, tt.want)
+			}
+		})
+	}
+}
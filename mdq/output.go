@@ -0,0 +1,853 @@
+package mdq
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// flattenCSV puts a value on a single line for --csv-flatten, replacing
+// newlines with spaces and collapsing whitespace
+func flattenCSV(s string) string {
+	// Remove newlines and extra whitespace for CSV
+	s = strings.ReplaceAll(s, "\n", " ")
+	s = strings.ReplaceAll(s, "\r", " ")
+	// Collapse multiple spaces
+	s = strings.Join(strings.Fields(s), " ")
+	return s
+}
+
+// formatCSV formats results as CSV, or as TSV with opts.TSVOutput.
+// Multi-line values are kept intact and quoted by the csv writer unless
+// opts.CSVFlatten is set.
+func formatCSV(results []*QueryResult, opts Options) string {
+	if len(results) == 0 {
+		return ""
+	}
+	if opts.Links {
+		return formatLinksCSV(results, opts)
+	}
+	if opts.Images {
+		return formatImagesCSV(results, opts)
+	}
+
+	var output strings.Builder
+	writer := csv.NewWriter(&output)
+	if opts.TSVOutput {
+		writer.Comma = '\t'
+	}
+
+	// Collect query names (preserve order from first occurrence)
+	queryNames := []string{}
+	seenQueries := make(map[string]bool)
+
+	for _, result := range results {
+		if result.Query != "" && !seenQueries[result.Query] {
+			queryNames = append(queryNames, result.Query)
+			seenQueries[result.Query] = true
+		}
+	}
+
+	// Write header
+	header := []string{"file"}
+	header = append(header, queryNames...)
+	writer.Write(header)
+
+	// Group results by file
+	type fileData struct {
+		file   string
+		values map[string]string
+	}
+
+	fileMap := make(map[string]*fileData)
+	var fileOrder []string
+
+	for _, result := range results {
+		if _, ok := fileMap[result.File]; !ok {
+			fileMap[result.File] = &fileData{
+				file:   result.File,
+				values: make(map[string]string),
+			}
+			fileOrder = append(fileOrder, result.File)
+		}
+
+		// Get value for this query - CSV should only use Body (not the label/heading)
+		var value string
+		if result.Body != "" {
+			value = result.Body
+		}
+		// For CSV, empty properties should remain empty, not show the field name
+
+		if opts.CSVFlatten {
+			value = flattenCSV(value)
+		}
+		fileMap[result.File].values[result.Query] = value
+	}
+
+	// Write rows
+	for _, fileName := range fileOrder {
+		fd := fileMap[fileName]
+		row := []string{fd.file}
+
+		for _, queryName := range queryNames {
+			row = append(row, fd.values[queryName])
+		}
+
+		writer.Write(row)
+	}
+
+	writer.Flush()
+	return strings.TrimRight(output.String(), "\n")
+}
+
+// filterResultsByType keeps only the results produced by queries of the given type
+func filterResultsByType(results []*QueryResult, queryType string) []*QueryResult {
+	var filtered []*QueryResult
+	for _, result := range results {
+		if result.Type == queryType {
+			filtered = append(filtered, result)
+		}
+	}
+	return filtered
+}
+
+// squeezeBlankLines collapses each run of blank lines into a single empty
+// line, like cat -s
+func squeezeBlankLines(s string) string {
+	lines := strings.Split(s, "\n")
+	squeezed := lines[:0]
+	previousBlank := false
+	for _, line := range lines {
+		blank := strings.TrimSpace(line) == ""
+		if blank && previousBlank {
+			continue
+		}
+		if blank {
+			line = ""
+		}
+		squeezed = append(squeezed, line)
+		previousBlank = blank
+	}
+	return strings.Join(squeezed, "\n")
+}
+
+// limitHeadings keeps results up to and including the nth one with a heading
+func limitHeadings(results []*QueryResult, n int) []*QueryResult {
+	count := 0
+	for i, result := range results {
+		if result.Heading == "" {
+			continue
+		}
+		count++
+		if count == n {
+			return results[:i+1]
+		}
+	}
+	return results
+}
+
+// headingSeparator returns the separator between a heading and its body,
+// falling back to the formatter's default when none was given
+func headingSeparator(opts Options, defaultSep string) string {
+	if opts.HeadingSep != "" {
+		return opts.HeadingSep
+	}
+	return defaultSep
+}
+
+// FormatOutput formats query results for display
+func FormatOutput(results []*QueryResult, opts Options) string {
+	results = PrepareResults(results, opts)
+
+	if opts.CSVOutput || opts.TSVOutput {
+		return formatCSV(results, opts)
+	}
+	if opts.JSONOutput {
+		return formatJSON(results, opts)
+	}
+	if opts.YAMLOutput {
+		return formatYAML(results, opts)
+	}
+	if opts.MarkdownOutput {
+		return formatMarkdown(results, opts)
+	}
+	if opts.HTMLOutput {
+		return formatHTML(results, opts)
+	}
+	return formatText(results, opts)
+}
+
+// PrepareResults applies the output options that transform results
+// regardless of the output format: filtering, body substitutions, and caps
+func PrepareResults(results []*QueryResult, opts Options) []*QueryResult {
+	// Drop results of the query kind that wasn't asked for
+	if opts.FrontmatterOnly {
+		results = filterResultsByType(results, "frontmatter")
+	} else if opts.SectionsOnly {
+		results = filterResultsByType(results, "section")
+	}
+
+	// Outside of JSON results the hash stands in for the body
+	if opts.Hash && (!opts.JSONOutput || opts.ObjectOutput) {
+		hashed := make([]*QueryResult, len(results))
+		for i, result := range results {
+			hashed[i] = result
+			if result.Hash != "" {
+				copied := *result
+				copied.Body = result.Hash
+				hashed[i] = &copied
+			}
+		}
+		results = hashed
+	}
+
+	// Outside of JSON results the line range stands in for the body
+	if opts.Lines && (!opts.JSONOutput || opts.ObjectOutput) {
+		ranged := make([]*QueryResult, len(results))
+		for i, result := range results {
+			ranged[i] = result
+			if result.Type == "section" && result.Start != 0 {
+				copied := *result
+				copied.Body = fmt.Sprintf("%d-%d", result.Start, result.End)
+				ranged[i] = &copied
+			}
+		}
+		results = ranged
+	}
+
+	// Fill the results of queries that matched nothing with the default
+	// value; a match with empty content keeps its empty content
+	if opts.Default != "" {
+		filled := make([]*QueryResult, len(results))
+		for i, result := range results {
+			filled[i] = result
+			if !result.Matched || result.Missing {
+				copied := *result
+				copied.Body = opts.Default
+				filled[i] = &copied
+			}
+		}
+		results = filled
+	}
+
+	// Strip inline formatting from bodies
+	if opts.Plain {
+		plain := make([]*QueryResult, len(results))
+		for i, result := range results {
+			copied := *result
+			copied.Body = toPlainText(result.Body, opts.PlainURLs)
+			if copied.Body != result.Body {
+				copied.Value = nil
+			}
+			plain[i] = &copied
+		}
+		results = plain
+	}
+
+	// Collapse runs of blank lines in bodies
+	if opts.SqueezeBlank {
+		squeezed := make([]*QueryResult, len(results))
+		for i, result := range results {
+			copied := *result
+			copied.Body = squeezeBlankLines(result.Body)
+			if copied.Body != result.Body {
+				copied.Value = nil
+			}
+			squeezed[i] = &copied
+		}
+		results = squeezed
+	}
+
+	// Drop the query from JSON results for callers expecting the old shape;
+	// object output still needs it for keys
+	if opts.NoQueryField && opts.JSONOutput && !opts.ObjectOutput {
+		unlabeled := make([]*QueryResult, len(results))
+		for i, result := range results {
+			copied := *result
+			copied.Query = ""
+			unlabeled[i] = &copied
+		}
+		results = unlabeled
+	}
+
+	// Cap the number of headings in head-only output
+	if opts.HeadOnly && opts.HeadLines > 0 {
+		results = limitHeadings(results, opts.HeadLines)
+	}
+
+	return results
+}
+
+// formatMarkdown formats results as markdown, including only the sections selected by the query
+func formatMarkdown(results []*QueryResult, opts Options) string {
+	var output strings.Builder
+
+	// Group results by file for better formatting
+	groups := groupByFile(results)
+
+	// Track if frontmatter has been added for each file
+	frontmatterAdded := make(map[string]bool)
+
+	// Format output
+	for gi, group := range groups {
+		hasFrontmatter := false
+
+		// Add file comment if multiple files
+		if len(groups) > 1 {
+			if gi > 0 {
+				output.WriteString("\n")
+			}
+			output.WriteString(fmt.Sprintf("<!-- File: %s -->\n\n", group.File))
+		}
+
+		// First pass: identify if there are frontmatter queries with non-empty values
+		for _, result := range group.Results {
+			// A frontmatter query will have result.Query that doesn't start with #
+			if result.Type != "section" {
+				// Only mark as having frontmatter if there's actual content
+				if result.Body != "" {
+					hasFrontmatter = true
+					break
+				}
+			}
+		}
+
+		// Output frontmatter if present
+		if hasFrontmatter && !frontmatterAdded[group.File] && opts.Pandoc {
+			writePandocFrontmatter(&output, group.Results)
+			frontmatterAdded[group.File] = true
+		} else if hasFrontmatter && !frontmatterAdded[group.File] {
+			output.WriteString("---\n")
+			for _, result := range group.Results {
+				// Only include frontmatter fields that were queried
+				if result.Type != "section" {
+					// Get the field name - use result.Heading if available, otherwise use result.Query
+					// (when -b flag is used, result.Heading will be empty)
+					fieldName := result.Heading
+					if fieldName == "" {
+						fieldName = result.Query
+					}
+
+					if result.Body != "" {
+						// Format the value
+						output.WriteString(fmt.Sprintf("%s: %s\n", fieldName, result.Body))
+					} else {
+						// Empty value
+						output.WriteString(fmt.Sprintf("%s: \"\"\n", fieldName))
+					}
+				}
+			}
+			output.WriteString("---\n\n")
+			frontmatterAdded[group.File] = true
+		}
+
+		// Output each section result
+		for ri, result := range group.Results {
+			// Skip frontmatter fields (already handled above)
+			if result.Type != "section" {
+				continue
+			}
+
+			// Skip empty results unless they were asked for
+			if result.Heading == "" && result.Body == "" && !opts.IncludeEmpty {
+				continue
+			}
+
+			// Add blank line between multiple section results
+			if ri > 0 {
+				output.WriteString("\n")
+			}
+
+			// Output heading if present
+			if result.Heading != "" && !opts.BodyOnly {
+				output.WriteString(result.Heading)
+				if result.Body != "" && !opts.HeadOnly {
+					output.WriteString(headingSeparator(opts, "\n\n"))
+				}
+			}
+
+			// Output body if present
+			if result.Body != "" && !opts.HeadOnly {
+				output.WriteString(result.Body)
+			}
+
+			output.WriteString("\n")
+		}
+	}
+
+	return strings.TrimRight(output.String(), "\n")
+}
+
+// pandocTitleFields are the frontmatter fields of a Pandoc title block, in order
+var pandocTitleFields = []string{"title", "author", "date"}
+
+// writePandocFrontmatter writes queried frontmatter as a Pandoc title block
+// ("% title", "% author", "% date"). Other fields go in a YAML block after it.
+func writePandocFrontmatter(output *strings.Builder, results []*QueryResult) {
+	titleBlock := make(map[string]string)
+	var otherFields []string
+
+	for _, result := range results {
+		if result.Type == "section" {
+			continue
+		}
+
+		fieldName := result.Heading
+		if fieldName == "" {
+			fieldName = result.Query
+		}
+
+		isTitleField := false
+		for _, name := range pandocTitleFields {
+			if fieldName == name {
+				isTitleField = true
+			}
+		}
+
+		if isTitleField {
+			titleBlock[fieldName] = strings.ReplaceAll(result.Body, "\n", " ")
+		} else if result.Body != "" {
+			otherFields = append(otherFields, fmt.Sprintf("%s: %s\n", fieldName, result.Body))
+		} else {
+			otherFields = append(otherFields, fmt.Sprintf("%s: \"\"\n", fieldName))
+		}
+	}
+
+	// Title block lines are positional, so missing ones before the last
+	// present field are written as a bare "%"
+	last := -1
+	for i, name := range pandocTitleFields {
+		if titleBlock[name] != "" {
+			last = i
+		}
+	}
+	for i := 0; i <= last; i++ {
+		if value := titleBlock[pandocTitleFields[i]]; value != "" {
+			output.WriteString("% " + value + "\n")
+		} else {
+			output.WriteString("%\n")
+		}
+	}
+	if last >= 0 {
+		output.WriteString("\n")
+	}
+
+	if len(otherFields) > 0 {
+		output.WriteString("---\n")
+		for _, field := range otherFields {
+			output.WriteString(field)
+		}
+		output.WriteString("---\n\n")
+	}
+}
+
+// formatJSON formats results as JSON
+func formatJSON(results []*QueryResult, opts Options) string {
+	// JSON Lines: one compact result, or with -o one file object, per line
+	if opts.JSONLines && opts.ObjectOutput {
+		var output strings.Builder
+		for _, obj := range fileObjects(results, opts) {
+			data, err := json.Marshal(obj)
+			if err != nil {
+				continue
+			}
+			output.Write(data)
+			output.WriteString("\n")
+		}
+		return strings.TrimRight(output.String(), "\n")
+	}
+	if opts.JSONLines {
+		return strings.TrimRight(formatJSONLines(results), "\n")
+	}
+
+	// Object output mode: combine multiple queries per file into single objects
+	if opts.ObjectOutput {
+		return formatJSONObject(results, opts)
+	}
+
+	// If only one result, output as single object
+	if len(results) == 1 {
+		data, err := json.MarshalIndent(results[0], "", "  ")
+		if err != nil {
+			return ""
+		}
+		return string(data)
+	}
+
+	// Multiple results, output as array
+	data, err := json.MarshalIndent(results, "", "  ")
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+// formatJSONLines formats each result as compact JSON on its own line,
+// always ending with a newline when there are results
+func formatJSONLines(results []*QueryResult) string {
+	var output strings.Builder
+	for _, result := range results {
+		data, err := json.Marshal(result)
+		if err != nil {
+			continue
+		}
+		output.Write(data)
+		output.WriteString("\n")
+	}
+	return output.String()
+}
+
+// formatJSONObject formats results as objects with query results as fields
+func formatJSONObject(results []*QueryResult, opts Options) string {
+	// Group results by file, keeping file order and query order
+	objects := fileObjects(results, opts)
+
+	// If only one file, return as single object
+	var value interface{} = objects
+	if len(objects) == 1 {
+		value = objects[0]
+	}
+
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+// fileObjects groups results into one object per file, in the order the
+// files first appear, with the query results as fields in query order.
+// Queries that found nothing are null.
+func fileObjects(results []*QueryResult, opts Options) []*orderedObject {
+	var objects []*orderedObject
+	byFile := make(map[string]*orderedObject)
+
+	for _, result := range results {
+		obj, ok := byFile[result.File]
+		if !ok {
+			obj = newOrderedObject()
+			obj.Set("file", result.File)
+			if opts.WithFormat {
+				obj.Set("frontmatterFormat", result.FrontmatterFormat)
+			}
+			byFile[result.File] = obj
+			objects = append(objects, obj)
+		}
+
+		// Use the query string as the key, or a friendlier name if requested
+		queryKey := result.Query
+		if queryKey == "" {
+			continue
+		}
+		if opts.JSONKeys == "title" || opts.JSONKeys == "field" {
+			queryKey = uniqueKey(obj, objectKey(result, opts.JSONKeys))
+		}
+		// A query that found nothing is null, so it can be told apart from
+		// a field that is present but empty
+		if !result.Matched && result.Body == "" {
+			obj.Set(queryKey, nil)
+			continue
+		}
+		obj.Set(queryKey, resultValue(result))
+	}
+	return objects
+}
+
+// resultValue returns a result's value for JSON and YAML: the typed
+// frontmatter value if there is one, or else the body
+func resultValue(result *QueryResult) interface{} {
+	if result.Value != nil {
+		return result.Value
+	}
+	return result.Body
+}
+
+// MarshalJSON writes a result with its typed frontmatter value, if any, as
+// the body. Every other field is written as tagged, so new fields show up
+// without changes here.
+func (r QueryResult) MarshalJSON() ([]byte, error) {
+	type alias QueryResult
+	a := alias(r)
+	if a.Value == nil && a.Body != "" {
+		a.Value = a.Body
+	}
+	return json.Marshal(a)
+}
+
+// formatYAML formats results like JSON object output: a YAML document for
+// a single file, or a sequence of them for several. Multi-line bodies come
+// out as block scalars.
+func formatYAML(results []*QueryResult, opts Options) string {
+	objects := fileObjects(results, opts)
+	if len(objects) == 0 {
+		return ""
+	}
+
+	var value interface{} = objects
+	if len(objects) == 1 {
+		value = objects[0]
+	}
+	data, err := yaml.Marshal(value)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimRight(string(data), "\n")
+}
+
+// objectKey returns the friendly object key for a result: the matched
+// section title or the frontmatter field name, depending on the mode
+func objectKey(result *QueryResult, mode string) string {
+	if mode == "field" && result.Type == "frontmatter" {
+		return result.Query
+	}
+	if result.Title != "" {
+		return result.Title
+	}
+	return result.Query
+}
+
+// uniqueKey de-duplicates a key against an object's existing keys by adding
+// a numeric suffix: "Notes", "Notes_2", "Notes_3", ...
+func uniqueKey(obj *orderedObject, key string) string {
+	if _, ok := obj.values[key]; !ok {
+		return key
+	}
+	for n := 2; ; n++ {
+		candidate := fmt.Sprintf("%s_%d", key, n)
+		if _, ok := obj.values[candidate]; !ok {
+			return candidate
+		}
+	}
+}
+
+// orderedObject is a JSON object that keeps its keys in insertion order,
+// so object output lists queries in the order they were given
+type orderedObject struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+// newOrderedObject creates an empty ordered object
+func newOrderedObject() *orderedObject {
+	return &orderedObject{values: make(map[string]interface{})}
+}
+
+// Set sets a key's value, appending the key if it is new
+func (o *orderedObject) Set(key string, value interface{}) {
+	if _, ok := o.values[key]; !ok {
+		o.keys = append(o.keys, key)
+	}
+	o.values[key] = value
+}
+
+// MarshalJSON writes the object's keys in insertion order
+func (o *orderedObject) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString("{")
+	for i, key := range o.keys {
+		if i > 0 {
+			buf.WriteString(",")
+		}
+		keyData, err := json.Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+		valueData, err := json.Marshal(o.values[key])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(keyData)
+		buf.WriteString(":")
+		buf.Write(valueData)
+	}
+	buf.WriteString("}")
+	return buf.Bytes(), nil
+}
+
+// MarshalYAML writes the object as a mapping with its keys in insertion order
+func (o *orderedObject) MarshalYAML() (interface{}, error) {
+	node := &yaml.Node{Kind: yaml.MappingNode}
+	for _, key := range o.keys {
+		valueNode := &yaml.Node{}
+		if str, ok := o.values[key].(string); ok {
+			valueNode = &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: str}
+			// The encoder drops a leading blank line from block scalars, so
+			// quote those values instead
+			if strings.HasPrefix(str, "\n") {
+				valueNode.Style = yaml.DoubleQuotedStyle
+			}
+		} else if err := valueNode.Encode(o.values[key]); err != nil {
+			return nil, err
+		}
+		node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, valueNode)
+	}
+	return node, nil
+}
+
+// oneLineEscaper escapes text so it fits on a single line. Backslashes are
+// escaped too, so the original text can always be recovered.
+var oneLineEscaper = strings.NewReplacer(
+	"\\", "\\\\",
+	"\n", "\\n",
+	"\r", "\\r",
+	"\t", "\\t",
+)
+
+// formatOneLine formats each result as a single line of the form
+// "file: heading: body", with the file only shown for multiple files
+func formatOneLine(results []*QueryResult, opts Options) string {
+	var output strings.Builder
+
+	// Only prefix the file name when results span multiple files
+	files := make(map[string]bool)
+	for _, result := range results {
+		files[result.File] = true
+	}
+
+	for _, result := range results {
+		// Skip empty results unless they were asked for
+		if result.Heading == "" && result.Body == "" && !opts.IncludeEmpty {
+			continue
+		}
+
+		var parts []string
+		if len(files) > 1 {
+			parts = append(parts, result.File)
+		}
+		if result.Heading != "" && !opts.BodyOnly {
+			parts = append(parts, oneLineEscaper.Replace(result.Heading))
+		}
+		if result.Body != "" && !opts.HeadOnly {
+			parts = append(parts, oneLineEscaper.Replace(result.Body))
+		}
+
+		output.WriteString(strings.Join(parts, ": "))
+		output.WriteString("\n")
+	}
+
+	return strings.TrimRight(output.String(), "\n")
+}
+
+// formatText formats results as plain text
+func formatText(results []*QueryResult, opts Options) string {
+	var output strings.Builder
+
+	// One-line mode: each result on a single grep-able line
+	if opts.OneLine {
+		return formatOneLine(results, opts)
+	}
+
+	// Verbatim mode: heading and body exactly as parsed, one block per result
+	if opts.Verbatim {
+		for _, result := range results {
+			// Skip empty results unless they were asked for
+			if result.Heading == "" && result.Body == "" && !opts.IncludeEmpty {
+				continue
+			}
+
+			if result.Heading != "" {
+				output.WriteString(result.Heading)
+				output.WriteString("\n")
+			}
+			output.WriteString(result.Body)
+			output.WriteString("\n")
+		}
+		return strings.TrimRight(output.String(), "\n")
+	}
+
+	// Raw mode: only output the found text
+	if opts.RawOutput {
+		for _, result := range results {
+			// Skip empty results unless they were asked for
+			if result.Heading == "" && result.Body == "" && !opts.IncludeEmpty {
+				continue
+			}
+
+			// Output heading if present
+			if result.Heading != "" && !opts.BodyOnly {
+				output.WriteString(result.Heading)
+				if result.Body != "" && !opts.HeadOnly {
+					output.WriteString(headingSeparator(opts, "\n"))
+				}
+			}
+
+			// Output body if present
+			if result.Body != "" && !opts.HeadOnly {
+				output.WriteString(result.Body)
+			}
+
+			output.WriteString("\n")
+		}
+		return strings.TrimRight(output.String(), "\n")
+	}
+
+	// Group results by file for better formatting
+	type fileGroup struct {
+		file    string
+		results []*QueryResult
+	}
+
+	var groups []fileGroup
+	currentFile := ""
+	var currentResults []*QueryResult
+
+	for _, result := range results {
+		if result.File != currentFile {
+			if currentFile != "" {
+				groups = append(groups, fileGroup{currentFile, currentResults})
+			}
+			currentFile = result.File
+			currentResults = []*QueryResult{result}
+		} else {
+			currentResults = append(currentResults, result)
+		}
+	}
+	if currentFile != "" {
+		groups = append(groups, fileGroup{currentFile, currentResults})
+	}
+
+	// Format output
+	for gi, group := range groups {
+		// Add file prefix if multiple files
+		if len(groups) > 1 {
+			if gi > 0 {
+				output.WriteString("\n")
+			}
+			output.WriteString(fmt.Sprintf("==> %s <==\n", group.file))
+		}
+
+		// Output each result
+		for ri, result := range group.results {
+			// Skip empty results unless they were asked for
+			if result.Heading == "" && result.Body == "" && !opts.IncludeEmpty {
+				continue
+			}
+
+			// Add blank line between multiple query results (but not for single query)
+			if ri > 0 && len(group.results) > 1 {
+				output.WriteString("\n")
+			}
+
+			// Output heading if present
+			if result.Heading != "" && !opts.BodyOnly {
+				output.WriteString(result.Heading)
+				if result.Body != "" && !opts.HeadOnly {
+					output.WriteString(headingSeparator(opts, "\n"))
+				}
+			}
+
+			// Output body if present
+			if result.Body != "" && !opts.HeadOnly {
+				output.WriteString(result.Body)
+			}
+
+			output.WriteString("\n")
+		}
+	}
+
+	return strings.TrimRight(output.String(), "\n")
+}
@@ -0,0 +1,142 @@
+package mdq
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDefaultOnlyFillsMisses(t *testing.T) {
+	doc, _ := ParseDocument("---\nempty:\n---\n## S\n", "a.md", false)
+	opts := Options{BodyOnly: true, RawOutput: true, IncludeEmpty: true, Default: "NA"}
+	var queries []*Query
+	for _, q := range []string{"empty", "missing", "##S", "##X", "##S[3]"} {
+		queries = append(queries, mustParseQuery(t, q))
+	}
+	results := ExecuteQueries([]*Document{doc}, queries, opts)
+
+	var got []string
+	for _, result := range PrepareResults(results, opts) {
+		got = append(got, result.Body)
+	}
+	want := []string{"", "NA", "", "NA", "NA"}
+	if len(got) != len(want) {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("result %d = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
+
+func TestCSVQuotesCommasQuotesAndNewlines(t *testing.T) {
+	body := "He said \"hi\", then left.\n\n```\na,b\n```"
+	doc, _ := ParseDocument("---\ntitle: \"A, \\\"B\\\"\"\n---\n## Notes\n"+body+"\n", "a.md", false)
+	queries := []*Query{mustParseQuery(t, "title"), mustParseQuery(t, "##Notes")}
+
+	for _, tsv := range []bool{false, true} {
+		opts := Options{CSVOutput: !tsv, TSVOutput: tsv}
+		output := FormatOutput(ExecuteQueries([]*Document{doc}, queries, opts), opts)
+
+		reader := csv.NewReader(strings.NewReader(output))
+		if tsv {
+			reader.Comma = '\t'
+		}
+		records, err := reader.ReadAll()
+		if err != nil {
+			t.Fatalf("tsv=%v: output doesn't parse: %v\n%s", tsv, err, output)
+		}
+		want := [][]string{{"file", "title", "##Notes"}, {"a.md", `A, "B"`, body}}
+		if !reflect.DeepEqual(records, want) {
+			t.Errorf("tsv=%v: records = %q, want %q", tsv, records, want)
+		}
+	}
+
+	// Flattened values stay on one line, with whitespace collapsed
+	opts := Options{CSVOutput: true, CSVFlatten: true}
+	output := FormatOutput(ExecuteQueries([]*Document{doc}, queries, opts), opts)
+	want := "file,title,##Notes\na.md,\"A, \"\"B\"\"\",\"He said \"\"hi\"\", then left. ``` a,b ```\""
+	if output != want {
+		t.Errorf("flattened output = %q, want %q", output, want)
+	}
+}
+
+func TestObjectOutputKeepsQueryOrder(t *testing.T) {
+	a, _ := ParseDocument("---\ntitle: T\nauthor: A\nzeta: Z\n---\n## Summary\nS\n", "a.md", false)
+	b, _ := ParseDocument("---\ntitle: U\n---\n", "b.md", false)
+	var queries []*Query
+	for _, q := range []string{"zeta", "title", "##Summary", "author", "missing"} {
+		queries = append(queries, mustParseQuery(t, q))
+	}
+	opts := Options{JSONOutput: true, ObjectOutput: true}
+
+	want := `[
+  {
+    "file": "a.md",
+    "zeta": "Z",
+    "title": "T",
+    "##Summary": "S",
+    "author": "A",
+    "missing": null
+  },
+  {
+    "file": "b.md",
+    "zeta": null,
+    "title": "U",
+    "##Summary": null,
+    "author": null,
+    "missing": null
+  }
+]`
+	// Map iteration order varies from run to run, so check several times
+	for i := 0; i < 20; i++ {
+		got := FormatOutput(ExecuteQueries([]*Document{a, b}, queries, opts), opts)
+		if got != want {
+			t.Fatalf("got:\n%s\nwant:\n%s", got, want)
+		}
+	}
+}
+
+func TestResultJSON(t *testing.T) {
+	tests := []struct {
+		name   string
+		result QueryResult
+		want   string
+	}{
+		{
+			name:   "section",
+			result: QueryResult{File: "a.md", Query: "##", Matched: true, Heading: "## S", Body: "text", Hash: "h", Start: 3, End: 4},
+			want:   `{"file":"a.md","query":"##","matched":true,"heading":"## S","body":"text","hash":"h","start":3,"end":4}`,
+		},
+		{
+			name:   "typed value",
+			result: QueryResult{File: "a.md", Query: "count", Matched: true, Body: "3", Value: 3, Start: 2},
+			want:   `{"file":"a.md","query":"count","matched":true,"body":3,"start":2}`,
+		},
+		{
+			name:   "link",
+			result: QueryResult{File: "a.md", Matched: true, Heading: "alt", Body: "x.png", Level: 2, Kind: "inline", Image: true, Start: 5, End: 5},
+			want:   `{"file":"a.md","matched":true,"heading":"alt","body":"x.png","level":2,"kind":"inline","image":true,"start":5,"end":5}`,
+		},
+		{
+			name:   "empty body",
+			result: QueryResult{File: "a.md", Query: "##", Matched: true, Type: "section", Title: "S", Missing: true},
+			want:   `{"file":"a.md","query":"##","matched":true}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := json.Marshal(&tt.result)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != tt.want {
+				t.Errorf("got %s, want %s", data, tt.want)
+			}
+		})
+	}
+}
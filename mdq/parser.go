@@ -0,0 +1,711 @@
+package mdq
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// ParseDocument parses a markdown file and extracts frontmatter and sections
+func ParseDocument(content string, filePath string, noBlocks bool) (*Document, error) {
+	return ParseDocumentReader(strings.NewReader(content), filePath, noBlocks)
+}
+
+// ParseDocumentReader parses markdown read from r line by line, without
+// first reading the whole input. The document holds one copy of the content
+// after the frontmatter (its Body), which the section bodies are sliced
+// from, so memory grows with the file but not with how deeply it nests.
+func ParseDocumentReader(r io.Reader, filePath string, noBlocks bool) (*Document, error) {
+	doc := &Document{
+		FilePath:    filePath,
+		Frontmatter: make(map[string]interface{}),
+		Sections:    []Section{},
+	}
+
+	reader := bufio.NewReader(r)
+	lineNum := 0
+	eof := false
+	var replay []string // Lines read ahead that turned out to be body
+
+	// nextLine returns the next line without its newline, so CRLF and LF
+	// line endings (even mixed) parse the same; the last line of the input
+	// is returned even when it is empty, as strings.Split would
+	nextLine := func() (string, bool, error) {
+		if len(replay) > 0 {
+			line := replay[0]
+			replay = replay[1:]
+			lineNum++
+			return line, true, nil
+		}
+		if eof {
+			return "", false, nil
+		}
+		line, err := reader.ReadString('\n')
+		if err == io.EOF {
+			eof = true
+		} else if err != nil {
+			return "", false, err
+		}
+		lineNum++
+		line = strings.TrimSuffix(line, "\n")
+		return strings.TrimSuffix(line, "\r"), true, nil
+	}
+
+	line, ok, err := nextLine()
+	if err != nil || !ok {
+		return doc, err
+	}
+
+	// Some editors start files with a UTF-8 byte order mark, which would
+	// hide a frontmatter fence or heading on the first line
+	line = strings.TrimPrefix(line, "\ufeff")
+
+	// Parse frontmatter if present: YAML between --- fences, TOML between +++
+	// fences, or a JSON object
+	if fence := strings.TrimSpace(line); fence == "---" || fence == "+++" {
+		frontmatterLines := []string{}
+		for {
+			line, ok, err = nextLine()
+			if err != nil {
+				return doc, err
+			}
+			if !ok || strings.TrimSpace(line) == fence {
+				break
+			}
+			frontmatterLines = append(frontmatterLines, line)
+		}
+
+		if len(frontmatterLines) > 0 {
+			doc.FieldLines = frontmatterFieldLines(frontmatterLines, fence)
+			frontmatterContent := strings.Join(frontmatterLines, "\n")
+			// A frontmatter error doesn't stop the sections from being parsed
+			if fence == "+++" {
+				_, doc.FrontmatterError = toml.Decode(frontmatterContent, &doc.Frontmatter)
+				normalizeTOMLTimes(doc.Frontmatter)
+				doc.FrontmatterFormat = "toml"
+			} else {
+				doc.FrontmatterError = yaml.Unmarshal([]byte(frontmatterContent), &doc.Frontmatter)
+				doc.FrontmatterFormat = "yaml"
+			}
+		}
+
+		// The body starts after the closing fence
+		if ok {
+			line, ok, err = nextLine()
+			if err != nil {
+				return doc, err
+			}
+		}
+	} else if fence == "{" || fence == "```json" {
+		// JSON frontmatter: an object opened by a lone { on the first line,
+		// or a ```json block. Lines are kept until the object is known to be
+		// frontmatter, since a block that isn't a JSON object is body.
+		read := []string{line}
+		var frontmatterLines []string
+		depth := jsonDepth(line, 0)
+		for {
+			line, ok, err = nextLine()
+			if err != nil {
+				return doc, err
+			}
+			if !ok {
+				break
+			}
+			read = append(read, line)
+			if fence == "```json" {
+				if strings.TrimSpace(line) == "```" {
+					break
+				}
+				frontmatterLines = append(frontmatterLines, line)
+				continue
+			}
+			if depth = jsonDepth(line, depth); depth == 0 {
+				break
+			}
+		}
+
+		firstLine := 2
+		if fence == "{" {
+			frontmatterLines, firstLine = read, 1
+		}
+		var frontmatter map[string]interface{}
+		if ok && json.Unmarshal([]byte(strings.Join(frontmatterLines, "\n")), &frontmatter) == nil && frontmatter != nil {
+			doc.Frontmatter = frontmatter
+			doc.FrontmatterFormat = "json"
+			doc.FieldLines = jsonFieldLines(frontmatterLines, firstLine)
+			line, ok, err = nextLine()
+			if err != nil {
+				return doc, err
+			}
+		} else {
+			// Not frontmatter after all, so parse every line read as body
+			line, ok, replay, lineNum = read[0], true, read[1:], 1
+		}
+	}
+
+	// Parse sections. The content after the frontmatter is kept once, and
+	// section bodies are sliced from it at the end, so they share its memory.
+	var body strings.Builder         // Full content after the frontmatter
+	var lineStarts []int             // Offset in body of each line
+	var headingLines []int           // Line in body of each section's heading
+	levelCounts := make(map[int]int) // Track count of each heading level
+	var open []int                   // Sections enclosing the current line
+	var fence codeFence
+
+	for ; ok; line, ok, err = nextLine() {
+		if len(lineStarts) > 0 {
+			body.WriteString("\n")
+		}
+		lineStarts = append(lineStarts, body.Len())
+		body.WriteString(line)
+
+		// Lines inside fenced code blocks are never headings
+		isFence := fence.update(line)
+
+		// Check if this is a heading; a line indented 4 or more columns is
+		// indented code instead
+		if isFence || fence.open() || indentWidth(line) >= 4 || !strings.HasPrefix(strings.TrimSpace(line), "#") {
+			continue
+		}
+
+		// Parse the new heading
+		level := 0
+		trimmed := strings.TrimSpace(line)
+		for i := 0; i < len(trimmed) && trimmed[i] == '#'; i++ {
+			level++
+		}
+
+		title := stripClosingHashes(strings.TrimSpace(trimmed[level:]))
+
+		levelCounts[level]++
+
+		// The heading ends sections at its level or above; the ones still
+		// open contain it, and are its ancestors
+		for len(open) > 0 && doc.Sections[open[len(open)-1]].Level >= level {
+			open = open[:len(open)-1]
+		}
+
+		doc.Sections = append(doc.Sections, Section{
+			Level:     level,
+			Title:     title,
+			Heading:   line,
+			Index:     levelCounts[level] - 1,
+			StartLine: lineNum,
+			Ancestors: append([]int(nil), open...),
+		})
+		headingLines = append(headingLines, len(lineStarts)-1)
+		open = append(open, len(doc.Sections)-1)
+	}
+	if err != nil {
+		return doc, err
+	}
+	doc.Body = body.String()
+
+	// text returns body lines [from, to) as they appeared, without the
+	// newline after the last one
+	text := func(from, to int) string {
+		if from >= to {
+			return ""
+		}
+		end := len(doc.Body)
+		if to < len(lineStarts) {
+			end = lineStarts[to] - 1
+		}
+		return doc.Body[lineStarts[from]:end]
+	}
+
+	for i := range doc.Sections {
+		// A body runs until the next heading of any level, a full body until
+		// the next one at the same or a higher level
+		first := headingLines[i] + 1
+		next, after := len(lineStarts), len(lineStarts)
+		if i+1 < len(doc.Sections) {
+			next = headingLines[i+1]
+		}
+		if j := subtreeEnd(doc.Sections, i); j < len(doc.Sections) {
+			after = headingLines[j]
+		}
+
+		sectionBody := text(first, next)
+		var sourceLines []int
+		if i == 0 && headingLines[0] > 0 {
+			// Lines before the first heading are part of the first body, so
+			// its lines no longer follow the heading
+			sectionBody = text(0, headingLines[0])
+			if first < next {
+				sectionBody += "\n" + text(first, next)
+			}
+			offset := doc.Sections[0].StartLine - headingLines[0]
+			for line := 0; line < next; line++ {
+				if line != headingLines[0] {
+					sourceLines = append(sourceLines, offset+line)
+				}
+			}
+		}
+		setSectionBody(&doc.Sections[i], sectionBody, sourceLines)
+		doc.Sections[i].FullBody = strings.TrimRight(text(first, after), "\n")
+	}
+
+	// Record each section's anchor for #/slug queries
+	for i, anchor := range documentAnchors(doc) {
+		doc.Sections[i].Anchor = anchor
+	}
+
+	// Apply --no-blocks filter if requested
+	if noBlocks {
+		for i := range doc.Sections {
+			section := &doc.Sections[i]
+			var kept []int
+			section.Body, kept = stripCodeBlocks(section.Body)
+			bodyLines := make([]int, len(kept))
+			for j, line := range kept {
+				bodyLines[j] = section.sourceLine(line)
+			}
+			section.bodyLines = bodyLines
+
+			section.FullBody, kept = stripCodeBlocks(section.FullBody)
+			section.fullBodyLines = make([]int, len(kept))
+			for j, line := range kept {
+				section.fullBodyLines[j] = section.StartLine + 1 + line
+			}
+		}
+		doc.Body = removeCodeBlocks(doc.Body)
+	}
+
+	return doc, nil
+}
+
+// normalizeTOMLTimes moves TOML local dates and times (which have no time
+// zone) to UTC, the way YAML dates are decoded, so they format the same
+func normalizeTOMLTimes(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, element := range v {
+			v[key] = normalizeTOMLTimes(element)
+		}
+	case []interface{}:
+		for i, element := range v {
+			v[i] = normalizeTOMLTimes(element)
+		}
+	case time.Time:
+		switch v.Location().String() {
+		case "datetime-local", "date-local", "time-local":
+			return time.Date(v.Year(), v.Month(), v.Day(), v.Hour(), v.Minute(), v.Second(), v.Nanosecond(), time.UTC)
+		}
+	}
+	return value
+}
+
+// frontmatterFieldLines finds the line of each top-level frontmatter field:
+// unindented "key:" lines in YAML, and "key =" or "[table]" lines in TOML.
+// The frontmatter starts on line 2, after the opening fence.
+func frontmatterFieldLines(lines []string, fence string) map[string]int {
+	fieldLines := make(map[string]int)
+	for i, line := range lines {
+		if line == "" || strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t") || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		var key string
+		if fence == "+++" {
+			if strings.HasPrefix(line, "[") {
+				key = strings.Trim(line, "[] ")
+			} else if eq := strings.Index(line, "="); eq > 0 {
+				key = line[:eq]
+			}
+			// Dotted keys and tables belong to their top-level field
+			key, _, _ = strings.Cut(key, ".")
+		} else if colon := strings.Index(line, ":"); colon > 0 && !strings.HasPrefix(line, "-") {
+			key = line[:colon]
+		}
+
+		key = strings.Trim(strings.TrimSpace(key), `"'`)
+		if _, seen := fieldLines[key]; key != "" && !seen {
+			fieldLines[key] = i + 2
+		}
+	}
+	return fieldLines
+}
+
+// jsonDepth returns the nesting depth of JSON objects and arrays after a
+// line, given the depth before it. Brackets inside strings don't count;
+// JSON strings can't span lines, so each line starts outside one.
+func jsonDepth(line string, depth int) int {
+	inString, escaped := false, false
+	for _, r := range line {
+		switch {
+		case escaped:
+			escaped = false
+		case inString && r == '\\':
+			escaped = true
+		case r == '"':
+			inString = !inString
+		case inString:
+		case r == '{' || r == '[':
+			depth++
+		case r == '}' || r == ']':
+			depth--
+		}
+	}
+	return depth
+}
+
+// jsonFieldLines finds the line of each top-level key of JSON frontmatter,
+// where lines[0] is line number first
+func jsonFieldLines(lines []string, first int) map[string]int {
+	fieldLines := make(map[string]int)
+	depth := 0
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if depth == 1 && strings.HasPrefix(trimmed, `"`) {
+			var key string
+			if end := strings.Index(trimmed[1:], `"`); end >= 0 && json.Unmarshal([]byte(trimmed[:end+2]), &key) == nil {
+				if _, seen := fieldLines[key]; !seen {
+					fieldLines[key] = first + i
+				}
+			}
+		}
+		depth = jsonDepth(line, depth)
+	}
+	return fieldLines
+}
+
+// stripClosingHashes removes an optional closing sequence of #s from a
+// heading title, as in "## Notes ##". The #s only close the heading when
+// preceded by a space, so "C#" keeps its hash.
+func stripClosingHashes(title string) string {
+	stripped := strings.TrimRight(title, "#")
+	if stripped == "" {
+		return ""
+	}
+	if stripped == title || !strings.HasSuffix(stripped, " ") && !strings.HasSuffix(stripped, "\t") {
+		return title
+	}
+	return strings.TrimSpace(stripped)
+}
+
+// setSectionBody sets a section's body, dropping trailing blank lines, and
+// records the line the body ends on. sourceLines gives the source line of
+// each body line, or is nil when the body directly follows the heading.
+func setSectionBody(section *Section, body string, sourceLines []int) {
+	section.Body = strings.TrimRight(body, "\n")
+	section.bodyLines = sourceLines
+	section.EndLine = section.StartLine
+	if section.Body != "" {
+		section.EndLine = max(section.StartLine, section.sourceLine(strings.Count(section.Body, "\n")))
+	}
+}
+
+// subtreeEnd returns the index of the first section after sections[i] at
+// the same or a higher level, or len(sections) if there is none
+func subtreeEnd(sections []Section, i int) int {
+	j := i + 1
+	for j < len(sections) && sections[j].Level > sections[i].Level {
+		j++
+	}
+	return j
+}
+
+// UseNestedBodies makes each section's body include its subsections, so
+// everything else (matching, output, line ranges) sees the nested content
+func UseNestedBodies(doc *Document) {
+	for i := range doc.Sections {
+		doc.Sections[i].Body = doc.Sections[i].FullBody
+		doc.Sections[i].bodyLines = doc.Sections[i].fullBodyLines
+		doc.Sections[i].EndLine = doc.Sections[subtreeEnd(doc.Sections, i)-1].EndLine
+	}
+}
+
+// frontmatterDepth returns the nesting depth of a frontmatter value, where a
+// scalar has depth 0. It walks the value iteratively and stops counting once
+// limit is exceeded, so arbitrarily deep input can't exhaust the stack.
+func frontmatterDepth(value interface{}, limit int) int {
+	type item struct {
+		value interface{}
+		depth int
+	}
+
+	maxDepth := 0
+	stack := []item{{value, 0}}
+	for len(stack) > 0 {
+		current := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		if current.depth > maxDepth {
+			maxDepth = current.depth
+		}
+		if maxDepth > limit {
+			return maxDepth
+		}
+
+		switch v := current.value.(type) {
+		case map[string]interface{}:
+			for _, child := range v {
+				stack = append(stack, item{child, current.depth + 1})
+			}
+		case []interface{}:
+			for _, child := range v {
+				stack = append(stack, item{child, current.depth + 1})
+			}
+		}
+	}
+	return maxDepth
+}
+
+// LimitFrontmatterDepth discards frontmatter nested deeper than maxDepth,
+// recording an error, so later traversal of untrusted input stays bounded
+func LimitFrontmatterDepth(doc *Document, maxDepth int) {
+	if maxDepth <= 0 {
+		return
+	}
+	if depth := frontmatterDepth(doc.Frontmatter, maxDepth); depth > maxDepth {
+		doc.Frontmatter = make(map[string]interface{})
+		doc.FrontmatterError = fmt.Errorf("frontmatter nested more than %d levels deep", maxDepth)
+	}
+}
+
+// LoadFrontmatterFile reads shared default frontmatter from a YAML file
+func LoadFrontmatterFile(path string) (map[string]interface{}, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	defaults := make(map[string]interface{})
+	if err := yaml.Unmarshal(content, &defaults); err != nil {
+		return nil, err
+	}
+	return defaults, nil
+}
+
+// MergeFrontmatter fills in top-level fields missing from a document's
+// frontmatter from the defaults. Fields the document sets win, even when
+// both values are maps.
+func MergeFrontmatter(doc *Document, defaults map[string]interface{}) {
+	if len(defaults) == 0 {
+		return
+	}
+	if doc.Frontmatter == nil {
+		doc.Frontmatter = make(map[string]interface{})
+	}
+	for key, value := range defaults {
+		if _, ok := doc.Frontmatter[key]; !ok {
+			doc.Frontmatter[key] = value
+		}
+	}
+}
+
+// codeFence tracks fenced code blocks line by line. A block opened with
+// ``` or ~~~ only ends at a bare fence of the same character that is at
+// least as long, as in CommonMark, so each kind can contain the other.
+type codeFence struct {
+	marker string // Opening fence of the current block, empty outside one
+	lang   string // First word of the opening fence's info string
+}
+
+// fenceMarker returns the run of three or more backticks or tildes a line
+// starts with after up to 3 columns of indentation, or "" if it isn't a
+// fence. As in CommonMark, a line indented 4 or more columns is indented
+// code, not a fence. The info string after the marker, such as a
+// language, doesn't matter, except that a backtick fence's info string
+// can't contain backticks, or the line would be inline code.
+func fenceMarker(line string) string {
+	if indentWidth(line) >= 4 {
+		return ""
+	}
+	trimmed := strings.TrimSpace(line)
+	if !strings.HasPrefix(trimmed, "```") && !strings.HasPrefix(trimmed, "~~~") {
+		return ""
+	}
+	n := 0
+	for n < len(trimmed) && trimmed[n] == trimmed[0] {
+		n++
+	}
+	if trimmed[0] == '`' && strings.Contains(trimmed[n:], "`") {
+		return ""
+	}
+	return trimmed[:n]
+}
+
+// update moves past a line, opening or closing a block, and reports whether
+// the line was a fence that did so
+func (f *codeFence) update(line string) bool {
+	marker := fenceMarker(line)
+	if marker == "" {
+		return false
+	}
+	if f.marker == "" {
+		f.marker = marker
+		f.lang = ""
+		if info := strings.Fields(strings.TrimSpace(line)[len(marker):]); len(info) > 0 {
+			f.lang = info[0]
+		}
+		return true
+	}
+	// A closing fence has nothing after it
+	if marker[0] == f.marker[0] && len(marker) >= len(f.marker) && strings.TrimSpace(line) == marker {
+		f.marker = ""
+		return true
+	}
+	return false
+}
+
+// open reports whether the last line was inside a fenced code block
+func (f *codeFence) open() bool {
+	return f.marker != ""
+}
+
+// CodeBlockOptions selects what KeepOnlyCodeBlocks keeps
+type CodeBlockOptions struct {
+	KeepFences bool   // Keep the fence lines around each block
+	FilterLang bool   // Keep only blocks whose language is Lang
+	Lang       string // Language to keep, compared case-insensitively; "" for blocks with none
+}
+
+// extractCodeBlocks keeps only the contents of fenced code blocks in text,
+// with a blank line between blocks
+func extractCodeBlocks(text string, opts CodeBlockOptions) string {
+	var blocks []string
+	var block []string
+	var fence codeFence
+	wanted := func() bool {
+		return !opts.FilterLang || strings.EqualFold(fence.lang, opts.Lang)
+	}
+	for _, line := range strings.Split(text, "\n") {
+		wasOpen := fence.open()
+		if fence.update(line) {
+			if opts.KeepFences {
+				block = append(block, line)
+			}
+			// A closing fence finishes the block
+			if wasOpen {
+				if wanted() {
+					blocks = append(blocks, strings.Join(block, "\n"))
+				}
+				block = nil
+			}
+			continue
+		}
+		if fence.open() {
+			block = append(block, line)
+		}
+	}
+
+	// An unclosed block runs to the end of the text
+	if fence.open() && wanted() {
+		blocks = append(blocks, strings.Join(block, "\n"))
+	}
+	return strings.Join(blocks, "\n\n")
+}
+
+// KeepOnlyCodeBlocks replaces every body in a document with just its fenced
+// code blocks, the opposite of parsing with noBlocks
+func KeepOnlyCodeBlocks(doc *Document, opts CodeBlockOptions) {
+	for i := range doc.Sections {
+		doc.Sections[i].Body = extractCodeBlocks(doc.Sections[i].Body, opts)
+		doc.Sections[i].FullBody = extractCodeBlocks(doc.Sections[i].FullBody, opts)
+	}
+	doc.Body = extractCodeBlocks(doc.Body, opts)
+}
+
+// listItemPattern matches the marker that starts a list item
+var listItemPattern = regexp.MustCompile(`^ {0,3}([-*+]|\d{1,9}[.)])(\s|$)`)
+
+// indentWidth returns how many columns a line is indented, with a tab
+// advancing to the next multiple of 4
+func indentWidth(line string) int {
+	width := 0
+	for _, c := range line {
+		switch c {
+		case ' ':
+			width++
+		case '\t':
+			width += 4 - width%4
+		default:
+			return width
+		}
+	}
+	return width
+}
+
+// removeCodeBlocks removes fenced and indented code blocks from text. An
+// indented block is a run of lines indented 4 or more columns after a
+// blank line, except in a list, where those lines continue the list item.
+func removeCodeBlocks(text string) string {
+	result, _ := stripCodeBlocks(text)
+	return result
+}
+
+// stripCodeBlocks is removeCodeBlocks, but also returns the 0-based line in
+// text of each line kept, so positions in the result can be traced back
+func stripCodeBlocks(text string) (string, []int) {
+	var result strings.Builder
+	var kept []int
+	scanner := bufio.NewScanner(bytes.NewBufferString(text))
+	var fence codeFence
+	inIndented := false // Inside an indented code block
+	inList := false     // Inside a list, where indented lines are continuations
+	previousBlank := true
+
+	for i := 0; scanner.Scan(); i++ {
+		line := scanner.Text()
+		blank := strings.TrimSpace(line) == ""
+
+		// Blank lines in and after an indented block are dropped, since the
+		// one before it already separates what's around it
+		if blank {
+			if !inIndented && !fence.open() {
+				result.WriteString("\n")
+				kept = append(kept, i)
+			}
+			previousBlank = true
+			continue
+		}
+
+		// An indented code block goes on until a line that isn't indented
+		indented := indentWidth(line) >= 4
+		if !fence.open() && indented && (inIndented || (previousBlank && !inList)) {
+			inIndented = true
+			previousBlank = false
+			continue
+		}
+		inIndented = false
+
+		wasBlank := previousBlank
+		previousBlank = false
+		if fence.update(line) || fence.open() {
+			continue
+		}
+
+		// A paragraph after a blank line ends a list; otherwise lines belong
+		// to the current item
+		if listItemPattern.MatchString(line) {
+			inList = true
+		} else if wasBlank && !indented && indentWidth(line) < 2 {
+			inList = false
+		}
+
+		result.WriteString(line)
+		result.WriteString("\n")
+		kept = append(kept, i)
+	}
+
+	return strings.TrimRight(result.String(), "\n"), kept
+}
+
+// sourceLine returns the 1-based source line of a 0-based line of the
+// section's body, accounting for code blocks removed by -n/--no-blocks
+func (s Section) sourceLine(bodyLine int) int {
+	if bodyLine >= 0 && bodyLine < len(s.bodyLines) {
+		return s.bodyLines[bodyLine]
+	}
+	return s.StartLine + 1 + bodyLine
+}
@@ -0,0 +1,224 @@
+package mdq
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFullBodyAndAncestors(t *testing.T) {
+	content := "# Guide\nintro\n## Install\nsteps\n### Linux\napt\n## Usage\nrun\n# Other\nend\n"
+	doc, err := ParseDocument(content, "a.md", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		title     string
+		body      string
+		fullBody  string
+		ancestors []int
+	}{
+		{"Guide", "intro", "intro\n## Install\nsteps\n### Linux\napt\n## Usage\nrun", nil},
+		{"Install", "steps", "steps\n### Linux\napt", []int{0}},
+		{"Linux", "apt", "apt", []int{0, 1}},
+		{"Usage", "run", "run", []int{0}},
+		{"Other", "end", "end", nil},
+	}
+	if len(doc.Sections) != len(tests) {
+		t.Fatalf("got %d sections, want %d", len(doc.Sections), len(tests))
+	}
+	for i, tt := range tests {
+		section := doc.Sections[i]
+		if section.Title != tt.title {
+			t.Errorf("section %d title = %q, want %q", i, section.Title, tt.title)
+		}
+		if section.Body != tt.body {
+			t.Errorf("%s body = %q, want %q", tt.title, section.Body, tt.body)
+		}
+		if section.FullBody != tt.fullBody {
+			t.Errorf("%s full body = %q, want %q", tt.title, section.FullBody, tt.fullBody)
+		}
+		if !reflect.DeepEqual(section.Ancestors, tt.ancestors) {
+			t.Errorf("%s ancestors = %v, want %v", tt.title, section.Ancestors, tt.ancestors)
+		}
+	}
+
+	// With nested bodies, an h1 runs to the next h1 and ends where it does
+	UseNestedBodies(doc)
+	if got := doc.Sections[0].EndLine; got != 8 {
+		t.Errorf("nested Guide end line = %d, want 8", got)
+	}
+}
+
+func TestRemoveCodeBlocksMixedFences(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{
+			"tildes containing backticks",
+			"before\n~~~\n```\n# not a heading\n```\n~~~\nafter",
+			"before\nafter",
+		},
+		{
+			"backticks containing tildes",
+			"before\n```go\n~~~\ncode\n~~~\n```\nafter",
+			"before\nafter",
+		},
+		{
+			"closing fence of the other kind",
+			"before\n~~~\ncode\n```\nstill code\n~~~\nafter",
+			"before\nafter",
+		},
+	}
+	for _, tt := range tests {
+		if got := removeCodeBlocks(tt.text); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestHeadingsInMixedFences(t *testing.T) {
+	content := "# A\n~~~\n```\n# not\n```\n~~~\n# B\n```\n~~~\n# not either\n~~~\n```\n"
+	doc, _ := ParseDocument(content, "a.md", false)
+	var titles []string
+	for _, section := range doc.Sections {
+		titles = append(titles, section.Title)
+	}
+	if want := []string{"A", "B"}; !reflect.DeepEqual(titles, want) {
+		t.Errorf("titles = %q, want %q", titles, want)
+	}
+}
+
+func TestFenceMarker(t *testing.T) {
+	tests := []struct {
+		line string
+		want string
+	}{
+		{"```", "```"},
+		{"````go", "````"},
+		{"~~~ python", "~~~"},
+		{"   ```", "```"},
+		{"    ```", ""},
+		{"\t```", ""},
+		{"``` `inline` ```", ""},
+		{"~~~ `ok`", "~~~"},
+		{"``", ""},
+	}
+	for _, tt := range tests {
+		if got := fenceMarker(tt.line); got != tt.want {
+			t.Errorf("fenceMarker(%q) = %q, want %q", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestIndentedFenceIsCode(t *testing.T) {
+	// An unmatched ``` in an indented code block doesn't swallow the
+	// headings after it
+	content := "# A\nExample:\n\n    ```\n    # code\n\n# B\ntext\n"
+	doc, _ := ParseDocument(content, "a.md", true)
+	if len(doc.Sections) != 2 || doc.Sections[1].Title != "B" {
+		t.Fatalf("got %d sections, want A and B", len(doc.Sections))
+	}
+	if got := doc.Sections[0].Body; got != "Example:" {
+		t.Errorf("A body with code removed = %q, want %q", got, "Example:")
+	}
+	if got := doc.Sections[1].Body; got != "text" {
+		t.Errorf("B body = %q, want %q", got, "text")
+	}
+}
+
+func TestCRLFMatchesLF(t *testing.T) {
+	lf := "---\ntitle: T\ntags: [a, b]\n---\n# Title\n\nbody line\n## Sub ##\n```\ncode\n```\n"
+	crlf := strings.ReplaceAll(lf, "\n", "\r\n")
+
+	for _, noBlocks := range []bool{false, true} {
+		want, _ := ParseDocument(lf, "a.md", noBlocks)
+		got, _ := ParseDocument(crlf, "a.md", noBlocks)
+		if !reflect.DeepEqual(got.Frontmatter, want.Frontmatter) {
+			t.Errorf("noBlocks=%v: CRLF frontmatter = %v, want %v", noBlocks, got.Frontmatter, want.Frontmatter)
+		}
+		if !reflect.DeepEqual(got.Sections, want.Sections) {
+			t.Errorf("noBlocks=%v: CRLF sections = %#v, want %#v", noBlocks, got.Sections, want.Sections)
+		}
+		if got.Body != want.Body {
+			t.Errorf("noBlocks=%v: CRLF body = %q, want %q", noBlocks, got.Body, want.Body)
+		}
+	}
+}
+
+func TestClosingHashes(t *testing.T) {
+	tests := []struct {
+		heading string
+		title   string
+	}{
+		{"### Title ###", "Title"},
+		{"### C# ###", "C#"},
+		{"### C#", "C#"},
+		{"## Notes ##   ", "Notes"},
+		{"## Notes #########", "Notes"},
+		{"## Issue #42", "Issue #42"},
+		{"## F#", "F#"},
+		{"## ##", ""},
+	}
+	for _, tt := range tests {
+		doc, _ := ParseDocument(tt.heading+"\nbody\n", "a.md", false)
+		if len(doc.Sections) != 1 {
+			t.Fatalf("%q: got %d sections, want 1", tt.heading, len(doc.Sections))
+		}
+		section := doc.Sections[0]
+		if section.Title != tt.title {
+			t.Errorf("%q: title = %q, want %q", tt.heading, section.Title, tt.title)
+		}
+		if section.Heading != tt.heading {
+			t.Errorf("%q: heading = %q, want it verbatim", tt.heading, section.Heading)
+		}
+	}
+
+	// Titles compare without their closing hashes
+	doc, _ := ParseDocument("### C# ###\n\n### Title ###\n", "a.md", false)
+	for _, q := range []string{"###C#", "###Title"} {
+		if !HasMatch(ExecuteQuery(doc, mustParseQuery(t, q), Options{})) {
+			t.Errorf("%s doesn't match", q)
+		}
+	}
+}
+
+func TestPreambleLines(t *testing.T) {
+	tests := []struct {
+		name       string
+		content    string
+		noBlocks   bool
+		start, end int
+		itemLines  []int
+	}{
+		{"preamble", "intro line\n\n# A\nbody\n", false, 3, 4, nil},
+		{"preamble only", "intro line\n\n# A\n", false, 3, 3, nil},
+		{"blank after frontmatter", "---\nt: 1\n---\n\n# A\nbody\n", false, 5, 6, nil},
+		{"list items", "---\nt: 1\n---\n- pre\n\n# A\n```\n- code\n```\n- item\n", false, 6, 10, []int{4, 10}},
+		{"list items without blocks", "---\nt: 1\n---\n- pre\n\n# A\n```\n- code\n```\n- item\n", true, 6, 10, []int{4, 10}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := ParseDocument(tt.content, "a.md", tt.noBlocks)
+			if err != nil {
+				t.Fatal(err)
+			}
+			section := doc.Sections[0]
+			if section.StartLine != tt.start || section.EndLine != tt.end {
+				t.Errorf("lines %d-%d, want %d-%d", section.StartLine, section.EndLine, tt.start, tt.end)
+			}
+
+			var itemLines []int
+			for _, item := range ExecuteQuery(doc, mustParseQuery(t, "#A/-"), Options{}) {
+				itemLines = append(itemLines, item.Start)
+			}
+			if !reflect.DeepEqual(itemLines, tt.itemLines) {
+				t.Errorf("list items on lines %v, want %v", itemLines, tt.itemLines)
+			}
+		})
+	}
+}
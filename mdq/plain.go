@@ -0,0 +1,82 @@
+package mdq
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	plainImage       = regexp.MustCompile(`!\[([^\]]*)\]\(([^)\s]*)[^)]*\)`)
+	plainLink        = regexp.MustCompile(`\[([^\]]*)\]\(([^)\s]*)[^)]*\)`)
+	plainRefLink     = regexp.MustCompile(`\[([^\]]+)\]\[[^\]]*\]`)
+	plainAutolink    = regexp.MustCompile(`<((?:https?|mailto):[^>\s]+)>`)
+	plainStarStrong  = regexp.MustCompile(`\*\*(\S(?:.*?\S)?)\*\*`)
+	plainUnderStrong = regexp.MustCompile(`__(\S(?:.*?\S)?)__`)
+	plainStrike      = regexp.MustCompile(`~~(\S(?:.*?\S)?)~~`)
+	plainStarEmph    = regexp.MustCompile(`\*(\S(?:[^*]*?\S)?)\*`)
+	plainUnderEmph   = regexp.MustCompile(`(^|\W)_(\S(?:[^_]*?\S)?)_(\W|$)`)
+	plainInlineCode  = regexp.MustCompile("`+([^`]*)`+")
+)
+
+// toPlainText strips inline markdown formatting from a body: emphasis
+// markers, inline code backticks, and links and images (reduced to their
+// text, followed by the URL in parentheses if withURLs is set). Code block
+// fences are dropped but their contents are kept as is.
+func toPlainText(body string, withURLs bool) string {
+	var output []string
+	var fence codeFence
+	for _, line := range strings.Split(body, "\n") {
+		if fence.update(line) {
+			continue
+		}
+		if fence.open() {
+			output = append(output, line)
+			continue
+		}
+		output = append(output, plainLine(line, withURLs))
+	}
+	return strings.Join(output, "\n")
+}
+
+// plainLine strips inline formatting from one line, leaving the contents of
+// code spans untouched apart from their backticks
+func plainLine(line string, withURLs bool) string {
+	var sb strings.Builder
+	last := 0
+	for _, span := range plainInlineCode.FindAllStringSubmatchIndex(line, -1) {
+		sb.WriteString(plainInline(line[last:span[0]], withURLs))
+		sb.WriteString(line[span[2]:span[3]])
+		last = span[1]
+	}
+	sb.WriteString(plainInline(line[last:], withURLs))
+	return sb.String()
+}
+
+// plainInline strips links and emphasis from text outside code spans
+func plainInline(text string, withURLs bool) string {
+	link := "$1"
+	if withURLs {
+		link = "$1 ($2)"
+	}
+	text = plainImage.ReplaceAllString(text, link)
+	text = plainLink.ReplaceAllString(text, link)
+	text = plainRefLink.ReplaceAllString(text, "$1")
+	text = plainAutolink.ReplaceAllString(text, "$1")
+	text = plainStarStrong.ReplaceAllString(text, "$1")
+	text = plainUnderStrong.ReplaceAllString(text, "$1")
+	text = plainStrike.ReplaceAllString(text, "$1")
+	text = plainStarEmph.ReplaceAllString(text, "$1")
+	return stripUnderEmph(text)
+}
+
+// stripUnderEmph removes _emphasis_ markers. A match consumes the characters
+// around its markers, so adjacent spans like "_a_ _b_" take another pass.
+func stripUnderEmph(text string) string {
+	for {
+		stripped := plainUnderEmph.ReplaceAllString(text, "$1$2$3")
+		if stripped == text {
+			return text
+		}
+		text = stripped
+	}
+}
@@ -0,0 +1,29 @@
+package mdq
+
+import "testing"
+
+func TestToPlainText(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		withURLs bool
+		want     string
+	}{
+		{"emphasis", "**bold** and *em* and ~~gone~~", false, "bold and em and gone"},
+		{"underscore emphasis", "_a_ and __b__", false, "a and b"},
+		{"adjacent underscore emphasis", "_a_ _b_ _c_", false, "a b c"},
+		{"adjacent after punctuation", "(_a_)_b_ x", false, "(a)b x"},
+		{"snake case", "snake_case_name stays", false, "snake_case_name stays"},
+		{"links", "[Go](https://go.dev) and ![logo](l.png)", true, "Go (https://go.dev) and logo (l.png)"},
+		{"code span", "`_x_` and _y_", false, "_x_ and y"},
+		{"code block", "```\n_x_\n```\n_y_", false, "_x_\ny"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := toPlainText(tt.body, tt.withURLs); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,843 @@
+package mdq
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Query syntax versions, selected with --query-syntax. Later versions add
+// metacharacters that older queries may have used literally in titles.
+const (
+	QuerySyntax1      = 1 // #Title and #Title[N], with literal titles
+	QuerySyntax2      = 2 // Adds ^/$ anchors, ?/REGEX/, {has=...}, [N,M,...], [-N], [start:end], * and ? wildcards, ~REGEX titles, *TITLE for any level, #/slug anchors, /- list items, and PARENT > STEP paths
+	QuerySyntaxLatest = QuerySyntax2
+)
+
+// AnyLevel is the Query.Level of a *TITLE query, which matches headings of
+// every level
+const AnyLevel = -1
+
+// ParseQuery parses a query string into a Query object using the latest syntax
+func ParseQuery(queryStr string) (*Query, error) {
+	return ParseQuerySyntax(queryStr, QuerySyntaxLatest)
+}
+
+// ParseQuerySyntax parses a query string into a Query object using the given
+// syntax version
+func ParseQuerySyntax(queryStr string, syntax int) (*Query, error) {
+	query := &Query{
+		Index:         0,     // Default to first match
+		ExplicitIndex: false, // Default to not explicitly specified
+		Syntax:        syntax,
+	}
+
+	// Registered custom query types take precedence over the built-in syntax
+	if qt, ok := lookupQueryType(queryStr); ok {
+		query.Type = "custom"
+		query.Prefix = qt.prefix
+		query.Field = strings.TrimPrefix(queryStr, qt.prefix)
+		return query, nil
+	}
+
+	// Check if it's a section query (starts with #, or * for any level)
+	anyLevel := syntax >= QuerySyntax2 && strings.HasPrefix(queryStr, "*")
+	if strings.HasPrefix(queryStr, "#") || anyLevel {
+		query.Type = "section"
+
+		// Check for a path: PARENT > STEP or PARENT/STEP matches STEP only
+		// where its enclosing section at PARENT's level matches PARENT
+		if parentStr, step, ok := splitPathQuery(queryStr); ok && syntax >= QuerySyntax2 {
+			parent, err := ParseQuerySyntax(parentStr, syntax)
+			if err != nil {
+				return nil, fmt.Errorf("invalid path parent: %v", err)
+			}
+			if parent.Type != "section" || parent.ExplicitIndex || parent.Slice || parent.ListItems {
+				return nil, fmt.Errorf("path parent must be a section query without an index or list items, got %q", parentStr)
+			}
+			query, err := ParseQuerySyntax(step, syntax)
+			if err != nil {
+				return nil, err
+			}
+			query.Parent = parent
+			return query, nil
+		}
+
+		// Count the heading level
+		level := 0
+		for i := 0; i < len(queryStr) && queryStr[i] == '#'; i++ {
+			level++
+		}
+		query.Level = level
+
+		// Get the rest after the # symbols
+		rest := queryStr[level:]
+		if anyLevel {
+			query.Level = AnyLevel
+			rest = queryStr[1:]
+		}
+
+		// Check for list items: /- for every item of the matched sections,
+		// or /-[N] for one of them
+		if matches := listItemsPattern.FindStringSubmatch(rest); matches != nil && syntax >= QuerySyntax2 {
+			rest = matches[1]
+			query.ListItems = true
+			if matches[2] != "" {
+				query.ItemIndex, _ = strconv.Atoi(matches[2])
+				query.ExplicitItemIndex = true
+			}
+		}
+
+		// Check for index in brackets: [N], or several: [N,M,...]. Negative
+		// indices count from the last match.
+		indexPattern := regexp.MustCompile(`^(.*?)\[(-?\d+(?:\s*,\s*-?\d+)*)]$`)
+		if syntax < QuerySyntax2 {
+			indexPattern = regexp.MustCompile(`^(.*?)\[(\d+)]$`)
+		}
+		slicePattern := regexp.MustCompile(`^(.*?)\[(-?\d*):(-?\d*)]$`)
+		if matches := slicePattern.FindStringSubmatch(rest); matches != nil && syntax >= QuerySyntax2 {
+			// Check for a slice of the matches: [start:end]
+			rest = matches[1]
+			query.Slice = true
+			query.SliceStart, _ = strconv.Atoi(matches[2])
+			query.SliceEnd, _ = strconv.Atoi(matches[3])
+			query.SliceOpenEnd = matches[3] == ""
+		} else if matches := indexPattern.FindStringSubmatch(rest); matches != nil {
+			rest = matches[1]
+			for _, field := range strings.Split(matches[2], ",") {
+				index, _ := strconv.Atoi(strings.TrimSpace(field))
+				query.Indices = append(query.Indices, index)
+			}
+			query.Index = query.Indices[0]
+			query.ExplicitIndex = true // Index was explicitly specified
+		} else {
+			query.Index = 0
+			query.ExplicitIndex = false // No explicit index
+		}
+
+		// Version 1 titles are literal
+		if syntax < QuerySyntax2 {
+			query.Title = strings.TrimSpace(rest)
+			return query, nil
+		}
+
+		// Check for an anchor: #/slug matches the section GitHub would link
+		// to as #slug, whatever its level
+		if level == 1 && len(rest) > 1 && rest[0] == '/' {
+			query.Level = AnyLevel
+			query.Slug = strings.TrimSpace(rest[1:])
+			return query, nil
+		}
+
+		// Check for a body predicate: ?/REGEX/
+		if start := strings.Index(rest, "?/"); start >= 0 && len(rest) > start+2 && strings.HasSuffix(rest, "/") {
+			pattern, err := regexp.Compile(rest[start+2 : len(rest)-1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid body pattern: %v", err)
+			}
+			query.BodyPattern = pattern
+			rest = rest[:start]
+		}
+
+		// Check for a descendant predicate: {has=SUBQUERY}
+		if start := strings.Index(rest, "{has="); start >= 0 && strings.HasSuffix(rest, "}") {
+			has, err := ParseQuerySyntax(strings.TrimSpace(rest[start+5:len(rest)-1]), syntax)
+			if err != nil {
+				return nil, fmt.Errorf("invalid has predicate: %v", err)
+			}
+			if has.Type != "section" {
+				return nil, fmt.Errorf("has predicate must be a section query, got %q", rest[start+5:len(rest)-1])
+			}
+			query.Has = has
+			rest = rest[:start]
+		}
+
+		query.Title = strings.TrimSpace(rest)
+
+		// Check for a regular expression title: ~REGEX
+		if strings.HasPrefix(query.Title, "~") {
+			query.Title = query.Title[1:]
+			pattern, err := regexp.Compile(query.Title)
+			if err != nil {
+				return nil, fmt.Errorf("invalid title pattern: %v", err)
+			}
+			query.TitlePattern = pattern
+			query.TitlePatternFold = regexp.MustCompile("(?i)" + query.Title)
+			return query, nil
+		}
+
+		// Check for anchors: ^Title (starts with) and Title$ (ends with).
+		// A backslash makes a leading ^ or trailing $ part of the title.
+		if strings.HasPrefix(query.Title, `\^`) {
+			query.Title = query.Title[1:]
+		} else if strings.HasPrefix(query.Title, "^") {
+			query.Title = query.Title[1:]
+			query.TitlePrefix = true
+		}
+		if strings.HasSuffix(query.Title, `\$`) {
+			query.Title = query.Title[:len(query.Title)-2] + "$"
+		} else if strings.HasSuffix(query.Title, "$") {
+			query.Title = query.Title[:len(query.Title)-1]
+			query.TitleSuffix = true
+		}
+
+		// Check for wildcards: * (any run of characters) and ? (any one character)
+		query.TitleGlob = strings.ContainsAny(query.Title, "*?")
+
+		return query, nil
+	}
+
+	// Otherwise, it's a frontmatter field query
+	query.Type = "frontmatter"
+	query.Field = queryStr
+
+	// A field like author.name or tags[0] walks into nested maps and lists
+	if strings.ContainsAny(queryStr, ".[") {
+		query.Path = parseFieldPath(queryStr)
+	}
+
+	return query, nil
+}
+
+// listItemsPattern matches the /- or /-[N] list item suffix of a section query
+var listItemsPattern = regexp.MustCompile(`^(.*)/-(?:\[(-?\d+)])?$`)
+
+// splitPathQuery splits a path query like ##Server > ###Config or
+// ##Server/###Config at its last step, returning the parent and the step.
+// Separators inside [...] and {...} don't count, and a step must be a
+// section query (a / step must start with #, as /- and #/slug use /).
+func splitPathQuery(queryStr string) (string, string, bool) {
+	depth := 0
+	for i := len(queryStr) - 1; i > 0; i-- {
+		switch c := queryStr[i]; c {
+		case ']', '}':
+			depth++
+		case '[', '{':
+			depth--
+		case '>', '/':
+			if depth != 0 {
+				continue
+			}
+			parent := strings.TrimSpace(queryStr[:i])
+			step := strings.TrimLeft(queryStr[i+1:], " ")
+			isStep := strings.HasPrefix(step, "#") || c == '>' && strings.HasPrefix(step, "*")
+			isParent := strings.HasPrefix(parent, "#") || strings.HasPrefix(parent, "*")
+			if isStep && isParent && !strings.HasSuffix(parent, "?") {
+				return parent, step, true
+			}
+		}
+	}
+	return "", "", false
+}
+
+// ExecuteQuery executes a query against a document
+func ExecuteQuery(doc *Document, query *Query, opts Options) []*QueryResult {
+	// Create a slice to hold the results
+	var results []*QueryResult
+
+	if query.Type == "custom" {
+		return executeCustomQuery(doc, query, opts)
+	}
+
+	if query.Type == "frontmatter" {
+		// Frontmatter queries always return a single result
+		result := newResult(doc, query)
+
+		value, ok := frontmatterValue(doc.Frontmatter, query)
+
+		// The mtime pseudo-field falls back to the file's modification time
+		if !ok && query.Field == "mtime" && !doc.ModTime.IsZero() {
+			value, ok = doc.ModTime, true
+		}
+
+		if !ok {
+			verbosef(opts, doc.FilePath, "%s: no such frontmatter field", query.Field)
+		} else {
+			verbosef(opts, doc.FilePath, "%s: matched frontmatter field", query.Field)
+			result.Matched = true
+
+			// Handle nil values (empty YAML fields) as empty strings
+			var bodyStr string
+			if t, isTime := value.(time.Time); isTime && (opts.DateFormat != "" || query.Field == "mtime") {
+				bodyStr = formatTime(t, opts)
+			} else if value != nil {
+				bodyStr = formatValue(value)
+			}
+
+			if !opts.HeadOnly {
+				result.Body = bodyStr
+				result.Value = typedValue(value)
+			}
+			result.Start = fieldLine(doc, query)
+			// In raw mode, don't set heading for frontmatter
+			if !opts.BodyOnly && !opts.RawOutput && !opts.Verbatim {
+				result.Heading = query.Field
+			}
+		}
+		return []*QueryResult{result}
+	}
+
+	// Query sections
+	var matches []Section
+	for i, section := range doc.Sections {
+		reason := sectionMismatch(section, query, opts)
+
+		// Check if the body length is within --min-body-lines/--max-body-lines
+		if reason == "" && !bodyLinesInRange(section, opts) {
+			reason = "body length out of range"
+		}
+
+		// Check if a descendant matches the has predicate (if specified)
+		if reason == "" && query.Has != nil && !hasDescendant(doc.Sections, i, query.Has, opts) {
+			reason = fmt.Sprintf("no subsection matches %s", formatQuery(query.Has))
+		}
+
+		// Check if the enclosing section matches the path parent (if specified)
+		if reason == "" && query.Parent != nil && !parentMatches(doc.Sections, i, query.Parent, opts) {
+			reason = fmt.Sprintf("not under %s", formatQuery(query.Parent))
+		}
+
+		if reason != "" {
+			verbosef(opts, doc.FilePath, "%s: rejected %q (line %d): %s", formatQuery(query), section.Heading, section.StartLine, reason)
+			continue
+		}
+		verbosef(opts, doc.FilePath, "%s: matched %q (line %d)", formatQuery(query), section.Heading, section.StartLine)
+
+		matches = append(matches, section)
+	}
+
+	// For a slice, only return the matches in the range
+	if query.Slice {
+		matches = sliceMatches(matches, query)
+	}
+
+	// For explicit indices, only return the matches at the specified
+	// positions, in the order given
+	if query.ExplicitIndex {
+		var selected []Section
+		var missing []*QueryResult
+		for _, requested := range query.Indices {
+			index := requested
+			if index < 0 {
+				index += len(matches)
+			}
+			if index < 0 || index >= len(matches) {
+				verbosef(opts, doc.FilePath, "%s: index %d out of range (%d matches)", formatQuery(query), requested, len(matches))
+				// For an explicit index that wasn't found, return an empty result
+				result := newResult(doc, query)
+				result.Missing = true
+				missing = append(missing, result)
+				results = append(results, result)
+				continue
+			}
+			selected = append(selected, matches[index])
+			results = append(results, nil)
+		}
+
+		// List item, footnote, quote, link, and image modes report on the selected
+		// sections as a whole
+		if query.ListItems {
+			return append(listItemResults(doc, query, selected, opts), missing...)
+		}
+		if opts.Footnotes {
+			return append(footnoteResults(doc, query, selected, opts), missing...)
+		}
+		if opts.Quotes {
+			return append(quoteResults(doc, query, selected, opts), missing...)
+		}
+		if opts.Links {
+			return append(linkResults(doc, query, selected, opts), missing...)
+		}
+		if opts.Images {
+			return append(imageResults(doc, query, selected, opts), missing...)
+		}
+
+		// Fill in the found positions between the missing ones
+		next := 0
+		for i, result := range results {
+			if result == nil {
+				results[i] = newResult(doc, query)
+				setSectionContent(results[i], selected[next], opts)
+				next++
+			}
+		}
+		return results
+	}
+
+	// List item queries report the items of the matched sections instead
+	if query.ListItems {
+		return listItemResults(doc, query, matches, opts)
+	}
+
+	// Footnote mode reports the footnotes of the matched sections instead
+	if opts.Footnotes {
+		return footnoteResults(doc, query, matches, opts)
+	}
+
+	// Quote mode reports the blockquotes of the matched sections instead
+	if opts.Quotes {
+		return quoteResults(doc, query, matches, opts)
+	}
+
+	// Link mode reports the links of the matched sections instead
+	if opts.Links {
+		return linkResults(doc, query, matches, opts)
+	}
+
+	// Image mode reports the images of the matched sections instead
+	if opts.Images {
+		return imageResults(doc, query, matches, opts)
+	}
+
+	for _, section := range matches {
+		result := newResult(doc, query)
+		setSectionContent(result, section, opts)
+		results = append(results, result)
+	}
+
+	return results
+}
+
+// fieldStepPattern matches one dot-separated part of a field path: a key
+// followed by any number of [N] indices
+var fieldStepPattern = regexp.MustCompile(`^([^\[\]]+)((?:\[\d+])*)$`)
+
+// fieldIndexPattern matches the numbers of a field path part's [N] indices
+var fieldIndexPattern = regexp.MustCompile(`\d+`)
+
+// parseFieldPath splits a field like authors[0].name into path steps, or
+// returns nil if it isn't a valid path
+func parseFieldPath(field string) []PathStep {
+	var path []PathStep
+	for _, part := range strings.Split(field, ".") {
+		matches := fieldStepPattern.FindStringSubmatch(part)
+		if matches == nil {
+			return nil
+		}
+		path = append(path, PathStep{Key: matches[1]})
+		for _, index := range fieldIndexPattern.FindAllString(matches[2], -1) {
+			n, _ := strconv.Atoi(index)
+			path = append(path, PathStep{Index: n, IsIndex: true})
+		}
+	}
+	return path
+}
+
+// fieldLine returns the source line of the top-level frontmatter field a
+// query reads, or 0 if it isn't known
+func fieldLine(doc *Document, query *Query) int {
+	if line, ok := doc.FieldLines[query.Field]; ok || len(query.Path) == 0 {
+		return line
+	}
+	return doc.FieldLines[query.Path[0].Key]
+}
+
+// frontmatterValue looks up a frontmatter query's field. A key that exists
+// as written wins; otherwise the field's path is followed through nested
+// maps and lists, and a missing key or out-of-range index anywhere along the
+// way means no value.
+func frontmatterValue(frontmatter map[string]interface{}, query *Query) (interface{}, bool) {
+	if value, ok := frontmatter[query.Field]; ok || query.Path == nil {
+		return value, ok
+	}
+
+	var value interface{} = frontmatter
+	for _, step := range query.Path {
+		if step.IsIndex {
+			list, isList := value.([]interface{})
+			if !isList || step.Index >= len(list) {
+				return nil, false
+			}
+			value = list[step.Index]
+			continue
+		}
+
+		m, isMap := value.(map[string]interface{})
+		if !isMap {
+			return nil, false
+		}
+		next, ok := m[step.Key]
+		if !ok {
+			return nil, false
+		}
+		value = next
+	}
+	return value, true
+}
+
+// formatValue formats a frontmatter value for text output: lists as their
+// elements joined with ", ", maps as key=value pairs in key order, and
+// anything else with %v. Lists and maps nested inside are bracketed as
+// [...] and {...} so they stay readable.
+func formatValue(value interface{}) string {
+	if value == nil {
+		return ""
+	}
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array:
+		elements := make([]string, v.Len())
+		for i := range elements {
+			elements[i] = formatNestedValue(v.Index(i).Interface())
+		}
+		return strings.Join(elements, ", ")
+	case reflect.Map:
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return fmt.Sprint(keys[i].Interface()) < fmt.Sprint(keys[j].Interface())
+		})
+		pairs := make([]string, len(keys))
+		for i, key := range keys {
+			pairs[i] = fmt.Sprintf("%v=%s", key.Interface(), formatNestedValue(v.MapIndex(key).Interface()))
+		}
+		return strings.Join(pairs, ", ")
+	}
+	return fmt.Sprintf("%v", value)
+}
+
+// formatNestedValue formats a value inside a list or map, bracketing lists
+// and maps so their elements aren't confused with the outer ones
+func formatNestedValue(value interface{}) string {
+	if value == nil {
+		return ""
+	}
+	switch reflect.ValueOf(value).Kind() {
+	case reflect.Slice, reflect.Array:
+		return "[" + formatValue(value) + "]"
+	case reflect.Map:
+		return "{" + formatValue(value) + "}"
+	}
+	return formatValue(value)
+}
+
+// typedValue returns a frontmatter value to keep its type in JSON and YAML
+// output: numbers, booleans, lists, and nested maps. Strings and dates use
+// the formatted body instead, as do values JSON can't represent.
+func typedValue(value interface{}) interface{} {
+	switch value.(type) {
+	case nil, string, time.Time:
+		return nil
+	}
+	if _, err := json.Marshal(value); err != nil {
+		return nil
+	}
+	return value
+}
+
+// ExecuteQueries runs every query against every document, in document order
+func ExecuteQueries(docs []*Document, queries []*Query, opts Options) []*QueryResult {
+	var results []*QueryResult
+	for _, doc := range docs {
+		for _, query := range queries {
+			queryResults := ExecuteQuery(doc, query, opts)
+
+			// Keep a placeholder for queries with no match so --default can
+			// fill it, and so every query has a field in object output
+			if len(queryResults) == 0 && (opts.Default != "" || opts.ObjectOutput || opts.YAMLOutput) {
+				queryResults = []*QueryResult{newResult(doc, query)}
+			}
+			results = append(results, queryResults...)
+		}
+	}
+	return results
+}
+
+// HasMatch reports whether any result matched a section or frontmatter
+// field, even one with empty content, ignoring placeholders for queries
+// that matched nothing
+func HasMatch(results []*QueryResult) bool {
+	for _, result := range results {
+		if result.Matched {
+			return true
+		}
+	}
+	return false
+}
+
+// sliceMatches returns the matches in a query's [start:end] range, with
+// negative bounds counting from the end and out-of-range bounds clamped
+func sliceMatches(matches []Section, query *Query) []Section {
+	clamp := func(i int) int {
+		if i < 0 {
+			i += len(matches)
+		}
+		if i < 0 {
+			return 0
+		}
+		if i > len(matches) {
+			return len(matches)
+		}
+		return i
+	}
+
+	start, end := clamp(query.SliceStart), len(matches)
+	if !query.SliceOpenEnd {
+		end = clamp(query.SliceEnd)
+	}
+	if start >= end {
+		return nil
+	}
+	return matches[start:end]
+}
+
+// sectionMatches reports whether a section satisfies a query's level, title,
+// and body constraints. The level is always checked, so sections with the
+// same title at different levels are never conflated.
+func sectionMatches(section Section, query *Query, opts Options) bool {
+	return sectionMismatch(section, query, opts) == ""
+}
+
+// bodyLinesInRange reports whether a section's body line count is within the
+// --min-body-lines and --max-body-lines bounds (0 for no bound). Blank lines
+// around the body are not counted.
+func bodyLinesInRange(section Section, opts Options) bool {
+	lines := 0
+	if body := strings.Trim(section.Body, "\n"); body != "" {
+		lines = strings.Count(body, "\n") + 1
+	}
+	if opts.MinBodyLines > 0 && lines < opts.MinBodyLines {
+		return false
+	}
+	if opts.MaxBodyLines > 0 && lines > opts.MaxBodyLines {
+		return false
+	}
+	return true
+}
+
+// hasDescendant reports whether any section nested under sections[i] (the
+// sections after it up to the next one at the same or a higher level)
+// matches the query
+func hasDescendant(sections []Section, i int, query *Query, opts Options) bool {
+	for j := i + 1; j < len(sections) && sections[j].Level > sections[i].Level; j++ {
+		if sectionMatches(sections[j], query, opts) &&
+			(query.Has == nil || hasDescendant(sections, j, query.Has, opts)) &&
+			(query.Parent == nil || parentMatches(sections, j, query.Parent, opts)) {
+			return true
+		}
+	}
+	return false
+}
+
+// parentMatches reports whether the section enclosing sections[i] at the
+// query's level (the immediate parent for an any-level query) matches the
+// query. Headings close sections at their own level, so there is at most one.
+func parentMatches(sections []Section, i int, query *Query, opts Options) bool {
+	ancestors := sections[i].Ancestors
+	for k := len(ancestors) - 1; k >= 0; k-- {
+		j := ancestors[k]
+		if query.Level != AnyLevel && sections[j].Level != query.Level {
+			continue
+		}
+		return sectionMatches(sections[j], query, opts) &&
+			(query.Has == nil || hasDescendant(sections, j, query.Has, opts)) &&
+			(query.Parent == nil || parentMatches(sections, j, query.Parent, opts))
+	}
+	return false
+}
+
+// titleMatches reports whether a section title satisfies a query's title,
+// taking ^ and $ anchors and --ignore-case into account
+func titleMatches(title string, query *Query, opts Options) bool {
+	want := query.Title
+	if opts.IgnoreCase {
+		title, want = strings.ToLower(title), strings.ToLower(want)
+	}
+
+	if query.TitlePattern != nil {
+		if opts.IgnoreCase {
+			return query.TitlePatternFold.MatchString(title)
+		}
+		return query.TitlePattern.MatchString(title)
+	}
+
+	if query.TitleGlob {
+		if query.TitlePrefix && !query.TitleSuffix {
+			want += "*"
+		} else if query.TitleSuffix && !query.TitlePrefix {
+			want = "*" + want
+		}
+		return globMatch(want, title)
+	}
+
+	switch {
+	case query.TitlePrefix && query.TitleSuffix:
+		return title == want
+	case query.TitlePrefix:
+		return strings.HasPrefix(title, want)
+	case query.TitleSuffix:
+		return strings.HasSuffix(title, want)
+	}
+	return title == want
+}
+
+// globMatch reports whether s matches a pattern in which * matches any run
+// of characters and ? matches any single character
+func globMatch(pattern, s string) bool {
+	p, str := []rune(pattern), []rune(s)
+	pi, si := 0, 0
+	star, starSi := -1, 0
+	for si < len(str) {
+		switch {
+		case pi < len(p) && (p[pi] == '?' || p[pi] == str[si]):
+			pi++
+			si++
+		case pi < len(p) && p[pi] == '*':
+			// Remember the star and try matching it against nothing first
+			star, starSi = pi, si
+			pi++
+		case star >= 0:
+			// Let the last star absorb one more character
+			starSi++
+			pi, si = star+1, starSi
+		default:
+			return false
+		}
+	}
+	for pi < len(p) && p[pi] == '*' {
+		pi++
+	}
+	return pi == len(p)
+}
+
+// setSectionContent fills a result's heading and body from a section,
+// honoring -h/-b and --strip-title unless verbatim output was requested
+func setSectionContent(result *QueryResult, section Section, opts Options) {
+	result.Matched = true
+	result.Title = section.Title
+	if !opts.HeadOnly || opts.Verbatim {
+		result.Body = section.Body
+	}
+	if !opts.BodyOnly || opts.Verbatim {
+		result.Heading = section.Heading
+	}
+
+	// In hash mode the body is replaced by its hash
+	if opts.Hash {
+		result.Hash = sectionHash(section.Body)
+		result.Body = ""
+	}
+
+	// In lines mode the body is replaced by the section's line range
+	result.Start = section.StartLine
+	result.End = section.EndLine
+	if opts.Lines {
+		result.Body = ""
+	}
+
+	// Drop the document title heading if requested
+	if opts.StripTitle && section.Level == 1 && !opts.Verbatim {
+		result.Heading = ""
+	}
+}
+
+// formatTime formats a date value with the --date-format layout, or RFC 3339 by default
+func formatTime(t time.Time, opts Options) string {
+	if opts.DateFormat != "" {
+		return t.Format(opts.DateFormat)
+	}
+	return t.Format(time.RFC3339)
+}
+
+// newResult creates an empty result for a query against a document
+func newResult(doc *Document, query *Query) *QueryResult {
+	return &QueryResult{
+		File:              doc.FilePath,
+		Query:             formatQuery(query),
+		Type:              query.Type,
+		FrontmatterFormat: doc.FrontmatterFormat,
+	}
+}
+
+// formatTitle converts a section query's title back to query syntax, with
+// its ~ or anchors, escaping a literal leading ^ or trailing $
+func formatTitle(q *Query) string {
+	if q.Slug != "" {
+		return "/" + q.Slug
+	}
+	if q.TitlePattern != nil {
+		return "~" + q.Title
+	}
+
+	title := q.Title
+	if strings.HasSuffix(title, "$") && !q.TitleSuffix {
+		title = title[:len(title)-1] + `\$`
+	}
+	if q.TitlePrefix {
+		title = "^" + title
+	} else if strings.HasPrefix(title, "^") {
+		title = `\` + title
+	}
+	if q.TitleSuffix {
+		title += "$"
+	}
+	return title
+}
+
+// formatQuery converts a Query back to a string representation
+func formatQuery(q *Query) string {
+	if q.Type == "frontmatter" {
+		return q.Field
+	}
+	if q.Type == "custom" {
+		return q.Prefix + q.Field
+	}
+
+	// Section query
+	var sb strings.Builder
+	if q.Parent != nil {
+		sb.WriteString(formatQuery(q.Parent) + " > ")
+	}
+	switch {
+	case q.Slug != "":
+		sb.WriteString("#")
+	case q.Level == AnyLevel:
+		sb.WriteString("*")
+	}
+	for i := 0; i < q.Level; i++ {
+		sb.WriteString("#")
+	}
+	if q.Syntax == QuerySyntax1 {
+		sb.WriteString(q.Title)
+		if q.ExplicitIndex {
+			sb.WriteString(fmt.Sprintf("[%d]", q.Index))
+		}
+		return sb.String()
+	}
+	sb.WriteString(formatTitle(q))
+	if q.Has != nil {
+		sb.WriteString("{has=" + formatQuery(q.Has) + "}")
+	}
+	if q.BodyPattern != nil {
+		sb.WriteString("?/" + q.BodyPattern.String() + "/")
+	}
+	if q.Slice {
+		sb.WriteString("[")
+		if q.SliceStart != 0 {
+			sb.WriteString(strconv.Itoa(q.SliceStart))
+		}
+		sb.WriteString(":")
+		if !q.SliceOpenEnd {
+			sb.WriteString(strconv.Itoa(q.SliceEnd))
+		}
+		sb.WriteString("]")
+	}
+	if q.ExplicitIndex {
+		indices := make([]string, len(q.Indices))
+		for i, index := range q.Indices {
+			indices[i] = strconv.Itoa(index)
+		}
+		sb.WriteString("[" + strings.Join(indices, ",") + "]")
+	}
+	if q.ListItems {
+		sb.WriteString("/-")
+		if q.ExplicitItemIndex {
+			sb.WriteString(fmt.Sprintf("[%d]", q.ItemIndex))
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,107 @@
+package mdq
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestHasMatch(t *testing.T) {
+	doc, _ := ParseDocument("---\ntitle:\n---\n## Empty\n", "a.md", false)
+
+	tests := []struct {
+		query string
+		opts  Options
+		want  bool
+	}{
+		{"##Empty", Options{}, true},
+		{"##Empty", Options{BodyOnly: true}, true},
+		{"##Empty", Options{BodyOnly: true, Hash: true}, true},
+		{"##Empty", Options{BodyOnly: true, Lines: true}, true},
+		{"title", Options{}, true},
+		{"##Missing", Options{}, false},
+		{"author", Options{}, false},
+		{"##[3]", Options{}, false},
+	}
+	for _, tt := range tests {
+		results := ExecuteQuery(doc, mustParseQuery(t, tt.query), tt.opts)
+		if got := HasMatch(results); got != tt.want {
+			t.Errorf("HasMatch(%q, %+v) = %v, want %v", tt.query, tt.opts, got, tt.want)
+		}
+	}
+}
+
+func TestMatchedMissingVsEmptyField(t *testing.T) {
+	doc, _ := ParseDocument("---\nempty:\nblank: \"\"\ntitle: T\n---\n## Empty\n", "a.md", false)
+
+	tests := []struct {
+		query string
+		want  bool
+	}{
+		{"empty", true},
+		{"blank", true},
+		{"title", true},
+		{"missing", false},
+		{"##Empty", true},
+		{"##Missing", false},
+	}
+	for _, tt := range tests {
+		for _, opts := range []Options{{}, {HeadOnly: true}, {BodyOnly: true}} {
+			results := ExecuteQuery(doc, mustParseQuery(t, tt.query), opts)
+			got := len(results) > 0 && results[0].Matched
+			if got != tt.want {
+				t.Errorf("%q with %+v: matched = %v, want %v", tt.query, opts, got, tt.want)
+			}
+		}
+	}
+
+	// JSON output tells an empty field from a missing one
+	queries := []*Query{mustParseQuery(t, "empty"), mustParseQuery(t, "missing")}
+	opts := Options{JSONOutput: true, IncludeEmpty: true}
+	got := FormatOutput(ExecuteQueries([]*Document{doc}, queries, opts), opts)
+	for _, want := range []string{`"query": "empty",
+    "matched": true`, `"query": "missing",
+    "matched": false`} {
+		if !strings.Contains(got, want) {
+			t.Errorf("JSON output lacks %q:\n%s", want, got)
+		}
+	}
+}
+
+func TestSameTitleAtSeveralLevels(t *testing.T) {
+	content, err := os.ReadFile("../test5.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, _ := ParseDocument(string(content), "test5.md", false)
+
+	tests := []struct {
+		query string
+		want  []string // Bodies of the matched sections, in order
+	}{
+		{"#Setup", []string{"Top-level setup."}},
+		{"##Setup", []string{"Setup nested under Setup.", "Reference setup, a sibling title under a different parent."}},
+		{"###Setup", []string{"Setup nested two levels deep."}},
+		{"##Setup[1]", []string{"Reference setup, a sibling title under a different parent."}},
+		{"*Setup", []string{"Top-level setup.", "Setup nested under Setup.", "Setup nested two levels deep.", "Reference setup, a sibling title under a different parent."}},
+		{"##Setup{has=###Setup}", []string{"Setup nested under Setup."}},
+		{"#Setup > ##Setup", []string{"Setup nested under Setup."}},
+		{"#Reference > ##Setup", []string{"Reference setup, a sibling title under a different parent."}},
+		{"##Setup > ###Setup", []string{"Setup nested two levels deep."}},
+		{"#Setup > ###Setup", []string{"Setup nested two levels deep."}},
+		{"#Reference > ###Setup", nil},
+		{"####Setup", nil},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, result := range ExecuteQuery(doc, mustParseQuery(t, tt.query), Options{BodyOnly: true}) {
+			if result.Matched {
+				got = append(got, strings.TrimSpace(result.Body))
+			}
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
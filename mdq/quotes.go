@@ -0,0 +1,82 @@
+package mdq
+
+import "strings"
+
+// Blockquote is a run of quoted lines at one nesting level
+type Blockquote struct {
+	Level int    // 1 for >, 2 for >>, and so on
+	Text  string // The quoted text with > markers removed
+}
+
+// quoteLevel returns the nesting level of a blockquote line and its text
+// with the markers removed. Markers may be separated by spaces ("> > x").
+func quoteLevel(line string) (int, string) {
+	level := 0
+	rest := strings.TrimLeft(line, " ")
+	for strings.HasPrefix(rest, ">") {
+		level++
+		rest = rest[1:]
+		if strings.HasPrefix(strings.TrimLeft(rest, " "), ">") {
+			// Another marker follows
+			rest = strings.TrimLeft(rest, " ")
+		} else {
+			// Drop the single optional space before the text
+			rest = strings.TrimPrefix(rest, " ")
+		}
+	}
+	return level, rest
+}
+
+// parseBlockquotes returns the blockquotes in a body, in order. Each change
+// in nesting level starts a new blockquote. Lines in fenced code blocks
+// aren't quotes, and a code block ends the quote before it.
+func parseBlockquotes(body string) []Blockquote {
+	var quotes []Blockquote
+	var lines []string
+	var fence codeFence
+	currentLevel := 0
+
+	flush := func() {
+		if currentLevel > 0 {
+			text := strings.Trim(strings.Join(lines, "\n"), "\n")
+			if text != "" {
+				quotes = append(quotes, Blockquote{Level: currentLevel, Text: text})
+			}
+		}
+		lines = nil
+	}
+
+	for _, line := range strings.Split(body, "\n") {
+		if fence.update(line) || fence.open() {
+			flush()
+			currentLevel = 0
+			continue
+		}
+		level, text := quoteLevel(line)
+		if level != currentLevel {
+			flush()
+			currentLevel = level
+		}
+		if level > 0 {
+			lines = append(lines, text)
+		}
+	}
+	flush()
+
+	return quotes
+}
+
+// quoteResults returns a result per blockquote in the matched sections
+func quoteResults(doc *Document, query *Query, sections []Section, opts Options) []*QueryResult {
+	var results []*QueryResult
+	for _, section := range sections {
+		for _, quote := range parseBlockquotes(section.Body) {
+			result := newResult(doc, query)
+			result.Matched = true
+			result.Body = quote.Text
+			result.Level = quote.Level
+			results = append(results, result)
+		}
+	}
+	return results
+}
@@ -0,0 +1,43 @@
+package mdq
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBlockquotesSkipFences(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want []Blockquote
+	}{
+		{
+			name: "plain quotes",
+			body: "> one\n>> two\n",
+			want: []Blockquote{{Level: 1, Text: "one"}, {Level: 2, Text: "two"}},
+		},
+		{
+			name: "quote in backtick fence",
+			body: "> real\n\n```text\n> not a quote\n```\n",
+			want: []Blockquote{{Level: 1, Text: "real"}},
+		},
+		{
+			name: "quote in tilde fence",
+			body: "~~~\n> not a quote\n>> nor this\n~~~\n> after\n",
+			want: []Blockquote{{Level: 1, Text: "after"}},
+		},
+		{
+			name: "fence splits a quote",
+			body: "> before\n```\ncode\n```\n> after\n",
+			want: []Blockquote{{Level: 1, Text: "before"}, {Level: 1, Text: "after"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseBlockquotes(tt.body); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package mdq
+
+import "strings"
+
+// QueryHandler executes a custom query against a document. arg is the query
+// string with the registered prefix removed.
+type QueryHandler func(doc *Document, arg string, opts Options) []*QueryResult
+
+// queryType is a registered custom query prefix and its handler
+type queryType struct {
+	prefix  string
+	handler QueryHandler
+}
+
+// queryTypes holds custom query types in registration order
+var queryTypes []queryType
+
+// RegisterQueryType registers a handler for queries starting with prefix.
+//
+// Custom query types are consulted before the built-in section and
+// frontmatter syntax, in the order they were registered; the first prefix
+// that matches a query wins. Handlers may leave File and Query unset on the
+// results they return and ExecuteQuery will fill them in; Type defaults to
+// "custom".
+//
+// RegisterQueryType panics if prefix is empty, fn is nil, or the prefix has
+// already been registered. It is not safe to call concurrently with queries
+// and is intended to be called during program initialization.
+func RegisterQueryType(prefix string, fn func(*Document, string, Options) []*QueryResult) {
+	if prefix == "" {
+		panic("mdq: RegisterQueryType prefix is empty")
+	}
+	if fn == nil {
+		panic("mdq: RegisterQueryType handler is nil")
+	}
+	for _, qt := range queryTypes {
+		if qt.prefix == prefix {
+			panic("mdq: RegisterQueryType called twice for prefix " + prefix)
+		}
+	}
+	queryTypes = append(queryTypes, queryType{prefix: prefix, handler: fn})
+}
+
+// lookupQueryType finds the first registered query type matching a query string
+func lookupQueryType(queryStr string) (queryType, bool) {
+	for _, qt := range queryTypes {
+		if strings.HasPrefix(queryStr, qt.prefix) {
+			return qt, true
+		}
+	}
+	return queryType{}, false
+}
+
+// executeCustomQuery runs a custom query's handler and fills in result metadata
+func executeCustomQuery(doc *Document, query *Query, opts Options) []*QueryResult {
+	var handler QueryHandler
+	for _, qt := range queryTypes {
+		if qt.prefix == query.Prefix {
+			handler = qt.handler
+			break
+		}
+	}
+	if handler == nil {
+		return nil
+	}
+
+	// Everything a handler returns is a match
+	results := handler(doc, query.Field, opts)
+	for _, result := range results {
+		result.Matched = true
+		if result.File == "" {
+			result.File = doc.FilePath
+		}
+		if result.Query == "" {
+			result.Query = formatQuery(query)
+		}
+		if result.Type == "" {
+			result.Type = query.Type
+		}
+		if result.FrontmatterFormat == "" {
+			result.FrontmatterFormat = doc.FrontmatterFormat
+		}
+	}
+	return results
+}
@@ -0,0 +1,74 @@
+package mdq
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MissingField is a required frontmatter field that a file lacks or leaves empty
+type MissingField struct {
+	File  string
+	Field string
+	Empty bool // The field is present but has no value
+}
+
+// ParseRequiredFields parses the comma-separated fields given to --require.
+// Each is a frontmatter field, or a path like author.name.
+func ParseRequiredFields(list string) ([]*Query, error) {
+	var fields []*Query
+	for _, field := range strings.Split(list, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		query, err := ParseQuery(field)
+		if err != nil {
+			return nil, err
+		}
+		if query.Type != "frontmatter" {
+			return nil, fmt.Errorf("%q is not a frontmatter field", field)
+		}
+		fields = append(fields, query)
+	}
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("no fields given")
+	}
+	return fields, nil
+}
+
+// CheckRequiredFields returns every required field that is missing or empty
+// in each document, in document order and then field order
+func CheckRequiredFields(docs []*Document, fields []*Query) []*MissingField {
+	var missing []*MissingField
+	for _, doc := range docs {
+		for _, field := range fields {
+			value, ok := frontmatterValue(doc.Frontmatter, field)
+			if ok && strings.TrimSpace(formatValue(value)) != "" {
+				continue
+			}
+			missing = append(missing, &MissingField{File: doc.FilePath, Field: field.Field, Empty: ok})
+		}
+	}
+	return missing
+}
+
+// FormatMissingFields reports missing required fields, one per line, with a
+// summary of how many files failed
+func FormatMissingFields(missing []*MissingField) string {
+	var output strings.Builder
+	files := make(map[string]bool)
+	for _, m := range missing {
+		problem := "missing"
+		if m.Empty {
+			problem = "empty"
+		}
+		output.WriteString(fmt.Sprintf("%s: %s required field %q\n", m.File, problem, m.Field))
+		files[m.File] = true
+	}
+	if len(files) == 1 {
+		output.WriteString("1 file is missing required fields\n")
+	} else if len(files) > 1 {
+		output.WriteString(fmt.Sprintf("%d files are missing required fields\n", len(files)))
+	}
+	return strings.TrimRight(output.String(), "\n")
+}
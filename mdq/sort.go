@@ -0,0 +1,92 @@
+package mdq
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// SortSpec describes how documents are ordered before output
+type SortSpec struct {
+	Field      string // Frontmatter field to sort by
+	Descending bool   // Sort from highest to lowest
+}
+
+// ParseSortSpec parses a --sort-by value of the form
+// "frontmatter:FIELD" with an optional ":asc" or ":desc" suffix
+func ParseSortSpec(spec string) (*SortSpec, error) {
+	parts := strings.Split(spec, ":")
+	if len(parts) < 2 || len(parts) > 3 || parts[0] != "frontmatter" || parts[1] == "" {
+		return nil, fmt.Errorf("expected frontmatter:FIELD[:asc|:desc], got %q", spec)
+	}
+
+	sortSpec := &SortSpec{Field: parts[1]}
+	if len(parts) == 3 {
+		switch parts[2] {
+		case "asc":
+		case "desc":
+			sortSpec.Descending = true
+		default:
+			return nil, fmt.Errorf("unknown sort direction %q (use asc or desc)", parts[2])
+		}
+	}
+	return sortSpec, nil
+}
+
+// SortDocuments stably sorts documents by a frontmatter field.
+// Documents missing the field always sort last, in their original order.
+func SortDocuments(docs []*Document, spec *SortSpec) {
+	sort.SliceStable(docs, func(i, j int) bool {
+		a, aOK := docs[i].Frontmatter[spec.Field]
+		b, bOK := docs[j].Frontmatter[spec.Field]
+		if !aOK || a == nil || !bOK || b == nil {
+			return aOK && a != nil && (!bOK || b == nil)
+		}
+
+		cmp := compareValues(a, b)
+		if spec.Descending {
+			return cmp > 0
+		}
+		return cmp < 0
+	})
+}
+
+// compareValues compares two frontmatter values, using time ordering when
+// both are dates (YAML dates or strings in a recognized date format),
+// numeric ordering when both are numbers, and string ordering otherwise
+func compareValues(a, b interface{}) int {
+	if at, ok := frontmatterDate(a); ok {
+		if bt, ok := frontmatterDate(b); ok {
+			return at.Compare(bt)
+		}
+	}
+
+	if af, ok := toFloat(a); ok {
+		if bf, ok := toFloat(b); ok {
+			switch {
+			case af < bf:
+				return -1
+			case af > bf:
+				return 1
+			}
+			return 0
+		}
+	}
+
+	return strings.Compare(fmt.Sprintf("%v", a), fmt.Sprintf("%v", b))
+}
+
+// toFloat converts a numeric frontmatter value to a float64
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case float64:
+		return n, true
+	}
+	return 0, false
+}
@@ -0,0 +1,43 @@
+package mdq
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSortDocumentsByDateStrings(t *testing.T) {
+	sources := []struct{ file, content string }{
+		{"json.md", "{\n\"date\": \"2024-03-01\"\n}\n# J\n"},
+		{"yaml.md", "---\ndate: 2024-01-15\n---\n# Y\n"},
+		{"words.md", "---\ndate: \"January 2, 2024\"\n---\n# W\n"},
+		{"toml.md", "+++\ndate = \"2023-12-31T23:00:00Z\"\n+++\n# T\n"},
+		{"none.md", "# N\n"},
+	}
+	var docs []*Document
+	for _, source := range sources {
+		doc, err := ParseDocument(source.content, source.file, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		docs = append(docs, doc)
+	}
+
+	tests := []struct {
+		descending bool
+		want       []string
+	}{
+		{false, []string{"toml.md", "words.md", "yaml.md", "json.md", "none.md"}},
+		{true, []string{"json.md", "yaml.md", "words.md", "toml.md", "none.md"}},
+	}
+	for _, tt := range tests {
+		sorted := append([]*Document(nil), docs...)
+		SortDocuments(sorted, &SortSpec{Field: "date", Descending: tt.descending})
+		var got []string
+		for _, doc := range sorted {
+			got = append(got, doc.FilePath)
+		}
+		if strings.Join(got, " ") != strings.Join(tt.want, " ") {
+			t.Errorf("descending=%v: got %v, want %v", tt.descending, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package mdq
+
+import "encoding/json"
+
+// SplitDocument is a document split into its frontmatter and body
+type SplitDocument struct {
+	File        string                 `json:"file"`
+	Frontmatter map[string]interface{} `json:"frontmatter"`
+	Body        string                 `json:"body"`
+}
+
+// FormatSplitJSON formats each document as a {file, frontmatter, body}
+// object: a single object for one document, an array for several
+func FormatSplitJSON(docs []*Document) string {
+	var split []SplitDocument
+	for _, doc := range docs {
+		split = append(split, SplitDocument{
+			File:        doc.FilePath,
+			Frontmatter: doc.Frontmatter,
+			Body:        doc.Body,
+		})
+	}
+
+	var value interface{} = split
+	if len(split) == 1 {
+		value = split[0]
+	} else if split == nil {
+		value = []SplitDocument{}
+	}
+
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
@@ -0,0 +1,103 @@
+package mdq
+
+import (
+	"strings"
+)
+
+// headingLevel returns the level of a markdown heading line, or 0 if the
+// line is not a heading (lines indented 4 or more columns are code)
+func headingLevel(line string) int {
+	if indentWidth(line) >= 4 {
+		return 0
+	}
+	trimmed := strings.TrimSpace(line)
+	level := 0
+	for level < len(trimmed) && trimmed[level] == '#' {
+		level++
+	}
+	return level
+}
+
+// shiftHeadings moves every heading in a body up by shift levels (never above
+// h1), leaving fenced code blocks alone
+func shiftHeadings(body string, shift int) string {
+	if shift <= 0 {
+		return body
+	}
+
+	lines := strings.Split(body, "\n")
+	var fence codeFence
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if fence.update(line) || fence.open() {
+			continue
+		}
+		if level := headingLevel(line); level > 0 {
+			remove := shift
+			if remove > level-1 {
+				remove = level - 1
+			}
+			lines[i] = trimmed[remove:]
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// standaloneFrontmatter returns the frontmatter to copy into a standalone
+// document: only the queried fields if there are any, otherwise all of it
+func standaloneFrontmatter(doc *Document, queries []*Query) map[string]interface{} {
+	var fields []string
+	for _, query := range queries {
+		if query.Type == "frontmatter" {
+			fields = append(fields, query.Field)
+		}
+	}
+	if len(fields) == 0 {
+		return doc.Frontmatter
+	}
+
+	frontmatter := make(map[string]interface{})
+	for _, field := range fields {
+		if value, ok := doc.Frontmatter[field]; ok {
+			frontmatter[field] = value
+		}
+	}
+	return frontmatter
+}
+
+// FormatStandalone formats each matched section as a complete markdown
+// document: the file's frontmatter followed by the section, with its heading
+// promoted to h1 and any headings below it shifted up to match
+func FormatStandalone(docs []*Document, queries []*Query, results []*QueryResult) (string, error) {
+	byFile := make(map[string]*Document)
+	for _, doc := range docs {
+		byFile[doc.FilePath] = doc
+	}
+
+	var output strings.Builder
+	for _, result := range results {
+		if result.Type != "section" || result.Heading == "" {
+			continue
+		}
+
+		if output.Len() > 0 {
+			output.WriteString("\n")
+		}
+
+		doc := byFile[result.File]
+		if frontmatter := standaloneFrontmatter(doc, queries); len(frontmatter) > 0 {
+			serialized, err := serializeFrontmatter(frontmatter, "yaml")
+			if err != nil {
+				return "", err
+			}
+			output.WriteString("---\n" + serialized + "\n---\n\n")
+		}
+
+		output.WriteString("# " + result.Title + "\n")
+		if body := strings.Trim(result.Body, "\n"); body != "" {
+			output.WriteString("\n" + shiftHeadings(body, headingLevel(result.Heading)-1) + "\n")
+		}
+	}
+
+	return strings.TrimRight(output.String(), "\n"), nil
+}
@@ -0,0 +1,67 @@
+package mdq
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// JSONStream writes results as one JSON array, a file at a time, so output
+// can start before all files are read and memory doesn't grow with the
+// number of results. Results appear in the order they are written.
+type JSONStream struct {
+	w       io.Writer
+	started bool
+	lines   bool // Write JSON Lines instead of an array
+}
+
+// NewJSONStream creates a stream that writes a JSON array to w
+func NewJSONStream(w io.Writer) *JSONStream {
+	return &JSONStream{w: w}
+}
+
+// NewJSONLinesStream creates a stream that writes each result to w as a
+// line of JSON, as --jsonl does
+func NewJSONLinesStream(w io.Writer) *JSONStream {
+	return &JSONStream{w: w, lines: true}
+}
+
+// Write appends results to the array, opening it on first use
+func (s *JSONStream) Write(results []*QueryResult) error {
+	if s.lines {
+		_, err := io.WriteString(s.w, formatJSONLines(results))
+		return err
+	}
+
+	for _, result := range results {
+		data, err := json.MarshalIndent(result, "  ", "  ")
+		if err != nil {
+			return err
+		}
+
+		sep := ",\n  "
+		if !s.started {
+			sep = "[\n  "
+			s.started = true
+		}
+		if _, err := io.WriteString(s.w, sep); err != nil {
+			return err
+		}
+		if _, err := s.w.Write(data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close closes the array, writing an empty one if no results were written
+func (s *JSONStream) Close() error {
+	if s.lines {
+		return nil
+	}
+	end := "\n]\n"
+	if !s.started {
+		end = "[]\n"
+	}
+	_, err := io.WriteString(s.w, end)
+	return err
+}
@@ -0,0 +1,100 @@
+package mdq
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+// benchmarkCorpus builds n documents with frontmatter and a dozen sections
+func benchmarkCorpus(b *testing.B, n int) []*Document {
+	b.Helper()
+	docs := make([]*Document, n)
+	for i := range docs {
+		var content strings.Builder
+		fmt.Fprintf(&content, "---\ntitle: Post %d\ndate: 2024-01-02\n---\n# Post %d\n", i, i)
+		for s := 0; s < 12; s++ {
+			fmt.Fprintf(&content, "## Section %d\n\n%s\n", s, strings.Repeat("Some body text for the section. ", 20))
+		}
+		doc, err := ParseDocument(content.String(), fmt.Sprintf("post%d.md", i), false)
+		if err != nil {
+			b.Fatal(err)
+		}
+		docs[i] = doc
+	}
+	return docs
+}
+
+func benchmarkQueries(b *testing.B) []*Query {
+	b.Helper()
+	var queries []*Query
+	for _, q := range []string{"title", "##"} {
+		query, err := ParseQuery(q)
+		if err != nil {
+			b.Fatal(err)
+		}
+		queries = append(queries, query)
+	}
+	return queries
+}
+
+// BenchmarkJSONStream writes each file's results as it goes, as --stream does
+func BenchmarkJSONStream(b *testing.B) {
+	docs := benchmarkCorpus(b, 2000)
+	queries := benchmarkQueries(b)
+	opts := Options{JSONOutput: true}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		stream := NewJSONStream(io.Discard)
+		for _, doc := range docs {
+			if err := stream.Write(PrepareResults(ExecuteQueries([]*Document{doc}, queries, opts), opts)); err != nil {
+				b.Fatal(err)
+			}
+		}
+		if err := stream.Close(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkJSONBuffered collects every result before formatting, as -j
+// without --stream does
+func BenchmarkJSONBuffered(b *testing.B) {
+	docs := benchmarkCorpus(b, 2000)
+	queries := benchmarkQueries(b)
+	opts := Options{JSONOutput: true}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		io.WriteString(io.Discard, FormatOutput(ExecuteQueries(docs, queries, opts), opts))
+	}
+}
+
+func TestJSONStreamMatchesBuffered(t *testing.T) {
+	a, _ := ParseDocument("---\ntitle: A\n---\n## One\nx\n", "a.md", false)
+	b, _ := ParseDocument("## Two\ny\n", "b.md", false)
+	queries := []*Query{mustParseQuery(t, "title"), mustParseQuery(t, "##")}
+	opts := Options{JSONOutput: true}
+
+	var streamed strings.Builder
+	stream := NewJSONStream(&streamed)
+	for _, doc := range []*Document{a, b} {
+		stream.Write(PrepareResults(ExecuteQueries([]*Document{doc}, queries, opts), opts))
+	}
+	stream.Close()
+
+	var got, want []map[string]interface{}
+	if err := json.Unmarshal([]byte(streamed.String()), &got); err != nil {
+		t.Fatalf("streamed output isn't JSON: %v\n%s", err, streamed.String())
+	}
+	buffered := FormatOutput(ExecuteQueries([]*Document{a, b}, queries, opts), opts)
+	if err := json.Unmarshal([]byte(buffered), &want); err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("streamed %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,208 @@
+package mdq
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Table is a GFM pipe table found in the body of a matched section
+type Table struct {
+	File    string     // File of the section the table is in
+	Heading string     // Heading of the section the table is in
+	Header  []string   // Header cells
+	Rows    [][]string // Body rows, each as wide as the header
+}
+
+// delimiterCellPattern matches one cell of a table's delimiter row, with
+// optional colons for alignment
+var delimiterCellPattern = regexp.MustCompile(`^:?-+:?$`)
+
+// splitTableRow splits a table row into its trimmed cells. Leading and
+// trailing pipes are optional, and \| is a literal pipe inside a cell.
+func splitTableRow(line string) []string {
+	line = strings.TrimSpace(line)
+	line = strings.TrimPrefix(line, "|")
+	if strings.HasSuffix(line, "|") && !strings.HasSuffix(line, `\|`) {
+		line = line[:len(line)-1]
+	}
+
+	var cells []string
+	var cell strings.Builder
+	for i := 0; i < len(line); i++ {
+		switch {
+		case line[i] == '\\' && i+1 < len(line) && line[i+1] == '|':
+			cell.WriteByte('|')
+			i++
+		case line[i] == '|':
+			cells = append(cells, strings.TrimSpace(cell.String()))
+			cell.Reset()
+		default:
+			cell.WriteByte(line[i])
+		}
+	}
+	return append(cells, strings.TrimSpace(cell.String()))
+}
+
+// hasTablePipe reports whether a line has an unescaped pipe, as every table
+// row must
+func hasTablePipe(line string) bool {
+	return strings.Contains(strings.ReplaceAll(line, `\|`, ""), "|")
+}
+
+// isDelimiterRow reports whether a line is a table delimiter row with the
+// given number of cells, like |---|:--:|
+func isDelimiterRow(line string, cells int) bool {
+	if !hasTablePipe(line) {
+		return false
+	}
+	parts := splitTableRow(line)
+	if len(parts) != cells {
+		return false
+	}
+	for _, part := range parts {
+		if !delimiterCellPattern.MatchString(part) {
+			return false
+		}
+	}
+	return true
+}
+
+// parseTables returns the pipe tables in a body. A table is a header row,
+// a delimiter row with as many cells, and the rows after them up to a blank
+// line; rows are padded or cut to the header's width. Tables inside fenced
+// code blocks are ignored.
+func parseTables(body string) []*Table {
+	var tables []*Table
+	var fence codeFence
+	lines := strings.Split(body, "\n")
+
+	for i := 0; i < len(lines); i++ {
+		if fence.update(lines[i]) || fence.open() {
+			continue
+		}
+		if !hasTablePipe(lines[i]) || i+1 >= len(lines) {
+			continue
+		}
+		header := splitTableRow(lines[i])
+		if !isDelimiterRow(lines[i+1], len(header)) {
+			continue
+		}
+
+		table := &Table{Header: header}
+		i += 2
+		for ; i < len(lines) && strings.TrimSpace(lines[i]) != "" && hasTablePipe(lines[i]); i++ {
+			row := make([]string, len(header))
+			copy(row, splitTableRow(lines[i]))
+			table.Rows = append(table.Rows, row)
+		}
+		tables = append(tables, table)
+	}
+	return tables
+}
+
+// ExtractTables returns the tables in the bodies of matched section results,
+// in result order
+func ExtractTables(results []*QueryResult) []*Table {
+	var tables []*Table
+	for _, result := range results {
+		if result.Type != "section" || !result.Matched {
+			continue
+		}
+		for _, table := range parseTables(result.Body) {
+			table.File = result.File
+			table.Heading = result.Heading
+			tables = append(tables, table)
+		}
+	}
+	return tables
+}
+
+// rowObjects returns a table's rows as objects keyed by the header, in
+// column order. Repeated or empty header cells get unique keys.
+func rowObjects(table *Table) []*orderedObject {
+	keys := make([]string, len(table.Header))
+	seen := newOrderedObject()
+	for i, name := range table.Header {
+		if name == "" {
+			name = fmt.Sprintf("column%d", i+1)
+		}
+		keys[i] = uniqueKey(seen, name)
+		seen.Set(keys[i], nil)
+	}
+
+	objects := make([]*orderedObject, len(table.Rows))
+	for r, row := range table.Rows {
+		objects[r] = newOrderedObject()
+		for i, key := range keys {
+			objects[r].Set(key, row[i])
+		}
+	}
+	return objects
+}
+
+// FormatTables formats tables as CSV (TSV with opts.TSVOutput), separated
+// by blank lines, or as JSON: an array of row objects keyed by the header
+// for one table, or an array of {file, heading, rows} for several
+func FormatTables(tables []*Table, opts Options) string {
+	if len(tables) == 0 {
+		return ""
+	}
+
+	if opts.JSONOutput {
+		return formatTablesJSON(tables, opts)
+	}
+
+	var output strings.Builder
+	for i, table := range tables {
+		if i > 0 {
+			output.WriteString("\n")
+		}
+		writer := csv.NewWriter(&output)
+		if opts.TSVOutput {
+			writer.Comma = '\t'
+		}
+		writer.Write(table.Header)
+		writer.WriteAll(table.Rows)
+	}
+	return strings.TrimRight(output.String(), "\n")
+}
+
+// formatTablesJSON formats tables as JSON, or with opts.JSONLines as one
+// compact row object per line
+func formatTablesJSON(tables []*Table, opts Options) string {
+	if opts.JSONLines {
+		var output strings.Builder
+		for _, table := range tables {
+			for _, row := range rowObjects(table) {
+				data, err := json.Marshal(row)
+				if err != nil {
+					continue
+				}
+				output.Write(data)
+				output.WriteString("\n")
+			}
+		}
+		return strings.TrimRight(output.String(), "\n")
+	}
+
+	var value interface{} = rowObjects(tables[0])
+	if len(tables) > 1 {
+		objects := make([]*orderedObject, len(tables))
+		for i, table := range tables {
+			objects[i] = newOrderedObject()
+			objects[i].Set("file", table.File)
+			objects[i].Set("heading", table.Heading)
+			objects[i].Set("rows", rowObjects(table))
+		}
+		value = objects
+	}
+
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
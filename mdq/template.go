@@ -0,0 +1,96 @@
+package mdq
+
+import (
+	"io"
+	"text/template"
+)
+
+// TemplateContext is the data available to output templates.
+//
+// Fields:
+//
+//	.Results  all query results, in output order
+//	.Files    the distinct files that produced results, in order
+//	.Queries  the distinct queries that produced results, in order
+//
+// Functions:
+//
+//	groupByFile RESULTS  groups results into {.File, .Results} per file
+type TemplateContext struct {
+	Results []*QueryResult
+	Files   []string
+	Queries []string
+}
+
+// FileResults is the set of results for a single file
+type FileResults struct {
+	File    string
+	Results []*QueryResult
+}
+
+// newTemplateContext builds the template context for a set of results
+func newTemplateContext(results []*QueryResult) *TemplateContext {
+	ctx := &TemplateContext{Results: results}
+
+	seenFiles := make(map[string]bool)
+	seenQueries := make(map[string]bool)
+	for _, result := range results {
+		if !seenFiles[result.File] {
+			ctx.Files = append(ctx.Files, result.File)
+			seenFiles[result.File] = true
+		}
+		if result.Query != "" && !seenQueries[result.Query] {
+			ctx.Queries = append(ctx.Queries, result.Query)
+			seenQueries[result.Query] = true
+		}
+	}
+
+	return ctx
+}
+
+// groupByFile groups results by file, preserving the order files first appear in
+func groupByFile(results []*QueryResult) []FileResults {
+	var groups []FileResults
+	index := make(map[string]int)
+	for _, result := range results {
+		i, ok := index[result.File]
+		if !ok {
+			i = len(groups)
+			index[result.File] = i
+			groups = append(groups, FileResults{File: result.File})
+		}
+		groups[i].Results = append(groups[i].Results, result)
+	}
+	return groups
+}
+
+// templateFuncs returns the helper functions available to output templates
+func templateFuncs() template.FuncMap {
+	return template.FuncMap{
+		"groupByFile": groupByFile,
+	}
+}
+
+// ParseTemplate compiles an output template, with the template helper
+// functions available
+func ParseTemplate(text string) (*template.Template, error) {
+	return template.New("output").Funcs(templateFuncs()).Parse(text)
+}
+
+// ExecuteTemplate writes each result through tmpl, with the *QueryResult
+// as the data (.File, .Query, .Heading, .Body, ...), or with
+// opts.TemplateOnce runs tmpl once with a *TemplateContext as the data.
+// Output options are applied to the results first; the template controls
+// all newlines.
+func ExecuteTemplate(w io.Writer, tmpl *template.Template, results []*QueryResult, opts Options) error {
+	results = PrepareResults(results, opts)
+	if opts.TemplateOnce {
+		return tmpl.Execute(w, newTemplateContext(results))
+	}
+	for _, result := range results {
+		if err := tmpl.Execute(w, result); err != nil {
+			return err
+		}
+	}
+	return nil
+}
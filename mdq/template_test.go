@@ -0,0 +1,53 @@
+package mdq
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExecuteTemplateOnce(t *testing.T) {
+	a, _ := ParseDocument("---\ntitle: A\n---\n## One\n\n## Two\n", "a.md", false)
+	b, _ := ParseDocument("---\ntitle: B\n---\n## Three\n", "b.md", false)
+	queries := []*Query{mustParseQuery(t, "##"), mustParseQuery(t, "title")}
+	results := ExecuteQueries([]*Document{a, b}, queries, Options{})
+
+	tmpl, err := ParseTemplate(`{{.Files}} {{.Queries}}{{range groupByFile .Results}} {{.File}}={{len .Results}}{{end}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out strings.Builder
+	if err := ExecuteTemplate(&out, tmpl, results, Options{TemplateOnce: true}); err != nil {
+		t.Fatal(err)
+	}
+	want := "[a.md b.md] [## title] a.md=3 b.md=2"
+	if out.String() != want {
+		t.Errorf("got %q, want %q", out.String(), want)
+	}
+}
+
+func TestExecuteTemplatePerResult(t *testing.T) {
+	doc, _ := ParseDocument("## One\n\n## Two\n", "a.md", false)
+	results := ExecuteQuery(doc, mustParseQuery(t, "##"), Options{})
+
+	tmpl, err := ParseTemplate(`{{.File}}:{{.Title}};`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out strings.Builder
+	if err := ExecuteTemplate(&out, tmpl, results, Options{}); err != nil {
+		t.Fatal(err)
+	}
+	if want := "a.md:One;a.md:Two;"; out.String() != want {
+		t.Errorf("got %q, want %q", out.String(), want)
+	}
+}
+
+// mustParseQuery parses a query with the latest syntax, failing the test on error
+func mustParseQuery(t *testing.T, s string) *Query {
+	t.Helper()
+	query, err := ParseQuery(s)
+	if err != nil {
+		t.Fatalf("ParseQuery(%q): %v", s, err)
+	}
+	return query
+}
@@ -0,0 +1,110 @@
+package mdq
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// TOCNode is a heading in a document's table of contents
+type TOCNode struct {
+	Title    string     `json:"title"`
+	Anchor   string     `json:"anchor"`
+	Level    int        `json:"level"`
+	Children []*TOCNode `json:"children"`
+}
+
+// slugify converts a heading title to a GitHub-style anchor: lowercase,
+// punctuation removed, and spaces replaced by hyphens
+func slugify(title string) string {
+	var sb strings.Builder
+	for _, r := range strings.ToLower(strings.TrimSpace(title)) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '_':
+			sb.WriteRune(r)
+		case r == ' ':
+			sb.WriteRune('-')
+		}
+	}
+	return sb.String()
+}
+
+// documentAnchors returns the anchor of each section in a document, with
+// duplicates suffixed -1, -2, ... the way GitHub disambiguates them
+func documentAnchors(doc *Document) []string {
+	anchors := make([]string, len(doc.Sections))
+	seen := make(map[string]int)
+	for i, section := range doc.Sections {
+		slug := slugify(section.Title)
+		if n, ok := seen[slug]; ok {
+			anchors[i] = fmt.Sprintf("%s-%d", slug, n)
+		} else {
+			anchors[i] = slug
+		}
+		seen[slug]++
+	}
+	return anchors
+}
+
+// buildTOC builds the heading hierarchy of a document. Headings deeper than
+// maxDepth are left out (0 means no limit).
+func buildTOC(doc *Document, maxDepth int) []*TOCNode {
+	anchors := documentAnchors(doc)
+	roots := []*TOCNode{}
+	var stack []*TOCNode
+
+	for i, section := range doc.Sections {
+		if maxDepth > 0 && section.Level > maxDepth {
+			continue
+		}
+
+		node := &TOCNode{
+			Title:    section.Title,
+			Anchor:   anchors[i],
+			Level:    section.Level,
+			Children: []*TOCNode{},
+		}
+
+		// Pop headings at the same or a deeper level; what's left is the parent
+		for len(stack) > 0 && stack[len(stack)-1].Level >= section.Level {
+			stack = stack[:len(stack)-1]
+		}
+
+		if len(stack) == 0 {
+			roots = append(roots, node)
+		} else {
+			parent := stack[len(stack)-1]
+			parent.Children = append(parent.Children, node)
+		}
+		stack = append(stack, node)
+	}
+
+	return roots
+}
+
+// FormatTOCJSON formats the table of contents of each document as JSON.
+// A single document yields its array of top-level headings; multiple
+// documents yield an array of {file, toc} objects.
+func FormatTOCJSON(docs []*Document, maxDepth int) string {
+	var value interface{}
+	if len(docs) == 1 {
+		value = buildTOC(docs[0], maxDepth)
+	} else {
+		type fileTOC struct {
+			File string     `json:"file"`
+			TOC  []*TOCNode `json:"toc"`
+		}
+		files := []fileTOC{}
+		for _, doc := range docs {
+			files = append(files, fileTOC{File: doc.FilePath, TOC: buildTOC(doc, maxDepth)})
+		}
+		value = files
+	}
+
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
@@ -0,0 +1,142 @@
+// Package mdq parses markdown documents and queries their frontmatter and
+// sections. The mdq command is a thin wrapper around it.
+package mdq
+
+import (
+	"regexp"
+	"time"
+)
+
+// Document represents a parsed markdown document
+type Document struct {
+	FilePath          string
+	Frontmatter       map[string]interface{}
+	FrontmatterFormat string         // "yaml", "toml", or "json", or empty if the document has no frontmatter
+	FrontmatterError  error          // Error from parsing the frontmatter, if any
+	FieldLines        map[string]int // Line of each top-level frontmatter field
+	Sections          []Section
+	Body              string    // Everything after the frontmatter
+	ModTime           time.Time // File modification time (zero for stdin)
+}
+
+// Section represents a markdown section (heading + content)
+type Section struct {
+	Level     int    // 1 for h1, 2 for h2, etc.
+	Title     string // Title text without the # symbols
+	Heading   string // The full heading line including #
+	Body      string // Content until the next heading of any level
+	FullBody  string // Content until the next heading of the same or a higher level, subsections included
+	Index     int    // Index among sections of the same level
+	StartLine int    // 1-based line number of the heading in the source file
+	EndLine   int    // 1-based line number of the last line of the body (StartLine if empty)
+	Anchor    string // GitHub-style anchor of the title, with -1, -2, ... for repeated titles
+	Ancestors []int  // Indices in Document.Sections of the enclosing sections, outermost first
+
+	bodyLines     []int // Source line of each Body line, when they don't directly follow the heading
+	fullBodyLines []int // The same for FullBody
+}
+
+// QueryResult represents the result of a query
+type QueryResult struct {
+	File              string      `json:"file"`
+	Query             string      `json:"query,omitempty"`
+	Matched           bool        `json:"matched"` // A section or frontmatter field matched, even if its content is empty
+	Type              string      `json:"-"`       // Type of the query that produced this result
+	Title             string      `json:"-"`       // Title of the matched section (section queries only)
+	Heading           string      `json:"heading,omitempty"`
+	Body              string      `json:"-"`               // Written to JSON through Value
+	Value             interface{} `json:"body,omitempty"`  // Frontmatter value with its original type, for JSON and YAML (nil to use Body)
+	Level             int         `json:"level,omitempty"` // Nesting level of an extracted blockquote
+	Hash              string      `json:"hash,omitempty"`  // SHA-256 of the normalized section body (--hash)
+	Kind              string      `json:"kind,omitempty"`  // Kind of an extracted link or image: "inline", "reference", "autolink", or "html"
+	Image             bool        `json:"image,omitempty"` // An extracted link is an image
+	Start             int         `json:"start,omitempty"` // First source line of the matched section, or the line of a frontmatter field
+	End               int         `json:"end,omitempty"`   // Last source line of the matched section
+	FrontmatterFormat string      `json:"-"`               // Format of the source document's frontmatter
+	Missing           bool        `json:"-"`               // An explicit index had no matching section
+}
+
+// Query represents a parsed query
+type Query struct {
+	Type              string         // "frontmatter", "section", or "custom"
+	Level             int            // For section queries: heading level (1, 2, 3, etc.)
+	Title             string         // For section queries: title to match (empty for any)
+	Slug              string         // For section queries: GitHub anchor to match (#/slug), at any level
+	Index             int            // Index to match (0 for first/default)
+	ExplicitIndex     bool           // Whether an index was explicitly specified using [N] syntax
+	Indices           []int          // All explicitly specified indices, in order ([N] or [N,M,...])
+	Slice             bool           // Whether a [start:end] slice of the matches was specified
+	SliceStart        int            // Slice start (negative counts from the end)
+	SliceEnd          int            // Slice end, exclusive (negative counts from the end)
+	SliceOpenEnd      bool           // Whether the slice end was omitted ([start:])
+	Field             string         // For frontmatter queries: field name; for custom queries: text after the prefix
+	Prefix            string         // For custom queries: the registered prefix
+	BodyPattern       *regexp.Regexp // For section queries: body must match this (nil for any)
+	TitlePrefix       bool           // For section queries: title only needs to start with Title (^Title)
+	TitleSuffix       bool           // For section queries: title only needs to end with Title (Title$)
+	TitleGlob         bool           // For section queries: Title contains * or ? wildcards
+	TitlePattern      *regexp.Regexp // For section queries: title must match this regular expression (~REGEX)
+	TitlePatternFold  *regexp.Regexp // TitlePattern, case-insensitive (for --ignore-case)
+	Has               *Query         // For section queries: a descendant section must match this (nil for any)
+	Parent            *Query         // For section queries: the nearest enclosing section at its level must match this (nil for any)
+	ListItems         bool           // For section queries: report the list items of matched sections (/-)
+	ItemIndex         int            // List item to report, with ExplicitItemIndex (negative counts from the end)
+	ExplicitItemIndex bool           // Whether a list item index was specified using /-[N] syntax
+	Syntax            int            // Query syntax version the query was parsed with
+	Path              []PathStep     // For frontmatter fields with . or [N]: the steps to follow (nil for a flat field)
+}
+
+// PathStep is one step of a frontmatter field path: a map key, or a list
+// index when IsIndex is set
+type PathStep struct {
+	Key     string
+	Index   int
+	IsIndex bool
+}
+
+// Options represents command-line options
+type Options struct {
+	HeadOnly        bool
+	BodyOnly        bool
+	JSONOutput      bool
+	JSONLines       bool // With JSONOutput, one compact JSON value per line
+	NoQueryField    bool // Leave the query out of JSON results
+	NoBlocks        bool
+	RawOutput       bool
+	ObjectOutput    bool
+	CSVOutput       bool
+	TSVOutput       bool // Tab-separated output, laid out like CSV
+	CSVFlatten      bool // Put each CSV or TSV value on one line instead of quoting multi-line values
+	MarkdownOutput  bool
+	HTMLOutput      bool   // Matched sections rendered to HTML fragments
+	YAMLOutput      bool   // One YAML mapping per file, like JSON object output
+	WithFormat      bool   // Include the frontmatter format in JSON object output
+	Verbatim        bool   // Emit section heading and body exactly as parsed, ignoring -h/-b
+	IncludeEmpty    bool   // Keep empty results in text and markdown output
+	OneLine         bool   // Collapse each text result onto one line with escaped newlines
+	FrontmatterOnly bool   // Only output results of frontmatter queries
+	SectionsOnly    bool   // Only output results of section queries
+	HeadingSep      string // Separator between heading and body (empty for the formatter default)
+	Pandoc          bool   // Markdown output with a Pandoc title block instead of YAML frontmatter
+	HeadLines       int    // Maximum number of headings in head-only output (0 for no limit)
+	DateFormat      string // Go time layout for date values (empty for the default)
+	StripTitle      bool   // Omit the heading line of h1 section results
+	Footnotes       bool   // Report footnotes of matched sections instead of their content
+	SqueezeBlank    bool   // Collapse runs of blank lines in bodies
+	Quotes          bool   // Report blockquotes of matched sections instead of their content
+	Links           bool   // Report links of matched sections instead of their content
+	Images          bool   // Report images of matched sections instead of their content
+	Default         string // Value output for queries with no match (empty for none)
+	JSONKeys        string // Object mode key: "query" (default), "title", or "field"
+	Hash            bool   // Report a hash of each matched section body instead of the body
+	MinBodyLines    int    // Only match sections with at least this many body lines (0 for no limit)
+	MaxBodyLines    int    // Only match sections with at most this many body lines (0 for no limit)
+	Verbose         bool   // Log query resolution per file to stderr
+	Lines           bool   // Report the source line range of each matched section instead of the body
+	Plain           bool   // Strip inline markdown formatting from bodies
+	PlainURLs       bool   // In plain mode, keep link URLs in parentheses after the link text
+	QuerySyntax     int    // Query syntax version for queries parsed after startup (REPL)
+	IgnoreCase      bool   // Compare section titles case-insensitively
+	Count           bool   // Report the number of matches of each query instead of the matches
+	TemplateOnce    bool   // Run the output template once over a TemplateContext instead of once per result
+}
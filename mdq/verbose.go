@@ -0,0 +1,42 @@
+package mdq
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// verbosef logs a query resolution message for a file to stderr when
+// --verbose is set
+func verbosef(opts Options, file string, format string, args ...interface{}) {
+	if !opts.Verbose {
+		return
+	}
+	fmt.Fprintf(os.Stderr, "mdq: %s: %s\n", file, fmt.Sprintf(format, args...))
+}
+
+// sectionMismatch explains why a section fails a query's level, title, or
+// body constraints, or returns "" if it satisfies them
+func sectionMismatch(section Section, query *Query, opts Options) string {
+	// Check if level matches
+	if query.Level != AnyLevel && section.Level != query.Level {
+		return fmt.Sprintf("level %d, want %d", section.Level, query.Level)
+	}
+
+	// Check if title matches (if specified)
+	if query.Title != "" && !titleMatches(section.Title, query, opts) {
+		return fmt.Sprintf("title %q does not match", section.Title)
+	}
+
+	// Check if the anchor matches (for #/slug queries)
+	if query.Slug != "" && section.Anchor != strings.ToLower(query.Slug) {
+		return fmt.Sprintf("anchor %q does not match", section.Anchor)
+	}
+
+	// Check if body matches the body predicate (if specified)
+	if query.BodyPattern != nil && !query.BodyPattern.MatchString(section.Body) {
+		return fmt.Sprintf("body does not match /%s/", query.BodyPattern)
+	}
+
+	return ""
+}
@@ -0,0 +1,97 @@
+package mdq
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Predicate is a frontmatter condition a file must satisfy to be queried
+type Predicate struct {
+	Field  string
+	Value  string
+	Negate bool // true for FIELD!=VALUE
+}
+
+// ParsePredicate parses a --where value of the form FIELD=VALUE or FIELD!=VALUE
+func ParsePredicate(expr string) (*Predicate, error) {
+	if i := strings.Index(expr, "!="); i > 0 {
+		return &Predicate{Field: strings.TrimSpace(expr[:i]), Value: strings.TrimSpace(expr[i+2:]), Negate: true}, nil
+	}
+	if i := strings.Index(expr, "="); i > 0 {
+		return &Predicate{Field: strings.TrimSpace(expr[:i]), Value: strings.TrimSpace(expr[i+1:])}, nil
+	}
+	return nil, fmt.Errorf("expected FIELD=VALUE or FIELD!=VALUE, got %q", expr)
+}
+
+// Matches reports whether a document's frontmatter satisfies the predicate.
+// A list value matches when any of its elements does. With fold set,
+// comparisons ignore case and diacritics.
+func (p *Predicate) Matches(doc *Document, fold bool) bool {
+	value, ok := doc.Frontmatter[p.Field]
+	matched := ok && valueMatches(value, p.Value, fold)
+	if p.Negate {
+		return !matched
+	}
+	return matched
+}
+
+// valueMatches compares a frontmatter value against a predicate value
+func valueMatches(value interface{}, want string, fold bool) bool {
+	if list, ok := value.([]interface{}); ok {
+		for _, item := range list {
+			if valueMatches(item, want, fold) {
+				return true
+			}
+		}
+		return false
+	}
+
+	got := predicateString(value)
+	if fold {
+		return foldString(got) == foldString(want)
+	}
+	return got == want
+}
+
+// predicateString converts a frontmatter value to a string for comparison.
+// YAML dates without a time of day compare as YYYY-MM-DD.
+func predicateString(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case time.Time:
+		if v.Hour() == 0 && v.Minute() == 0 && v.Second() == 0 && v.Nanosecond() == 0 {
+			return v.Format("2006-01-02")
+		}
+		return v.Format(time.RFC3339)
+	}
+	return fmt.Sprintf("%v", value)
+}
+
+// foldString normalizes a string for case- and accent-insensitive comparison
+// by decomposing it, dropping combining marks, and case folding
+func foldString(s string) string {
+	t := transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+	stripped, _, err := transform.String(t, s)
+	if err != nil {
+		stripped = s
+	}
+	return cases.Fold().String(stripped)
+}
+
+// MatchesAll reports whether a document satisfies every predicate
+func MatchesAll(doc *Document, predicates []*Predicate, fold bool) bool {
+	for _, p := range predicates {
+		if !p.Matches(doc, fold) {
+			return false
+		}
+	}
+	return true
+}
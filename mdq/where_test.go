@@ -0,0 +1,38 @@
+package mdq
+
+import "testing"
+
+func TestWhereFold(t *testing.T) {
+	doc, _ := ParseDocument("---\nauthor: José Núñez\ncity: Zürich\ntags: [Café, news]\n---\n", "a.md", false)
+
+	tests := []struct {
+		expr  string
+		exact bool
+		fold  bool
+	}{
+		{"author=José Núñez", true, true},
+		{"author=Jose Nunez", false, true},
+		{"author=JOSÉ NÚÑEZ", false, true},
+		{"author=jose nunez", false, true},
+		{"author!=Jose Nunez", true, false},
+		{"city=zurich", false, true},
+		{"city=Zurich", false, true},
+		{"tags=cafe", false, true},
+		{"tags=Café", true, true},
+		{"author=Josef Nunez", false, false},
+		// Composed and decomposed forms of é compare equal when folding
+		{"author=Jose\u0301 Nu\u0301n\u0303ez", false, true},
+	}
+	for _, tt := range tests {
+		p, err := ParsePredicate(tt.expr)
+		if err != nil {
+			t.Fatalf("ParsePredicate(%q): %v", tt.expr, err)
+		}
+		if got := p.Matches(doc, false); got != tt.exact {
+			t.Errorf("%q without --fold = %v, want %v", tt.expr, got, tt.exact)
+		}
+		if got := p.Matches(doc, true); got != tt.fold {
+			t.Errorf("%q with --fold = %v, want %v", tt.expr, got, tt.fold)
+		}
+	}
+}
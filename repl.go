@@ -0,0 +1,39 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/disser/mdq/mdq"
+)
+
+// runREPL reads queries from in line by line and writes the results for each
+// to out, until EOF. Documents are parsed once by the caller and reused.
+func runREPL(docs []*mdq.Document, opts mdq.Options, in io.Reader, out io.Writer) {
+	scanner := bufio.NewScanner(in)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		queries, err := parseQueries(line, opts.QuerySyntax)
+		if err != nil {
+			// A bad query shouldn't end the session
+			fmt.Fprintf(os.Stderr, "Error parsing query %v\n", err)
+			continue
+		}
+
+		output := mdq.FormatOutput(mdq.ExecuteQueries(docs, queries, opts), opts)
+		if output != "" {
+			fmt.Fprintln(out, output)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading stdin: %v\n", err)
+	}
+}